@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	defaultAdaptiveBudget = time.Second
+	defaultAdaptiveRelErr = 0.01
+	// the confidence interval is meaningless with too few runs, so at least this many are always performed
+	adaptiveMinRuns = 5
+)
+
+// AdaptiveOptions control when a codec has been repeated enough times
+type AdaptiveOptions struct {
+	// maximum time to spend repeating each codec
+	Budget time.Duration
+	// stop once the 95% confidence interval of the total time is within this fraction of the mean
+	MaxRelErr float64
+}
+
+// benchmarks every codec against the same input, repeating each one until its timings are stable or its
+// time budget is spent. Returns the input along with one aggregated result per codec
+func runAdaptiveBenchmark(src *inputSource, benchmarkers []Benchmarker, warmup int, opts *AdaptiveOptions, runOpts *RunOptions) ([]byte, []*BenchmarkResult, error) {
+	input, messages, err := getCheckedBenchmarkMessages(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	final := make([]*BenchmarkResult, 0, len(benchmarkers))
+	for _, benchmarker := range benchmarkers {
+		for range warmup {
+			if _, err := runBenchmarker(benchmarker, input, messages, runOpts); err != nil {
+				return nil, nil, fmt.Errorf("error while running benchmark: %v", err)
+			}
+		}
+		runs, err := runAdaptive(benchmarker, input, messages, opts, runOpts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error while running benchmark: %v", err)
+		}
+		final = append(final, aggregateRuns(runs))
+	}
+	return input, final, nil
+}
+
+// repeats a single codec until its timings are stable or its time budget is spent. Only the running
+// mean and variance are kept for the stopping rule, the full statistics are computed once at the end
+func runAdaptive(benchmarker Benchmarker, input []byte, messages [][]byte, opts *AdaptiveOptions, runOpts *RunOptions) ([]*BenchmarkResult, error) {
+	runs := []*BenchmarkResult{}
+	totalTimes := &runningStats{}
+	t0 := time.Now()
+	for {
+		result, err := runBenchmarker(benchmarker, input, messages, runOpts)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, result)
+		totalTimes.add(result.GetTotalTime())
+		if time.Since(t0) >= opts.Budget {
+			return runs, nil
+		}
+		if len(runs) >= adaptiveMinRuns && isStable(totalTimes, opts.MaxRelErr) {
+			return runs, nil
+		}
+	}
+}
+
+// returns true if the confidence interval is within the given fraction of the mean
+func isStable(stats *runningStats, maxRelErr float64) bool {
+	if stats.mean <= 0 {
+		return true
+	}
+	return stats.ci95()/stats.mean <= maxRelErr
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTimedBenchmarker implements the Benchmarker interface, returning the given times in a loop
+type TestTimedBenchmarker struct {
+	times []time.Duration
+	runs  int
+}
+
+func (tb *TestTimedBenchmarker) RunBenchmark(input []byte, opts *RunOptions) (*BenchmarkResult, error) {
+	t := tb.times[tb.runs%len(tb.times)]
+	tb.runs++
+	return &BenchmarkResult{
+		Name:         "test",
+		CompressTime: t,
+	}, nil
+}
+
+func TestRunAdaptive(t *testing.T) {
+	type testData struct {
+		name    string
+		times   []time.Duration
+		opts    AdaptiveOptions
+		expRuns int
+		minRuns int
+	}
+	tests := []testData{
+		{
+			name:    "stable timings stop after the minimum runs",
+			times:   []time.Duration{1000},
+			opts:    AdaptiveOptions{Budget: time.Minute, MaxRelErr: 0.01},
+			expRuns: adaptiveMinRuns,
+		},
+		{
+			name:    "noisy timings run until stable",
+			times:   []time.Duration{1000, 2000},
+			opts:    AdaptiveOptions{Budget: time.Minute, MaxRelErr: 0.05},
+			minRuns: adaptiveMinRuns + 1,
+		},
+		{
+			name:    "budget stops unstable timings",
+			times:   []time.Duration{1000, 2000},
+			opts:    AdaptiveOptions{Budget: time.Nanosecond, MaxRelErr: 0},
+			expRuns: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			benchmarker := &TestTimedBenchmarker{times: test.times}
+			runs, err := runAdaptive(benchmarker, []byte("input"), nil, &test.opts, NewRunOptions())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.expRuns != 0 && len(runs) != test.expRuns {
+				t.Errorf("expected %d runs but got %d", test.expRuns, len(runs))
+			}
+			if len(runs) < test.minRuns {
+				t.Errorf("expected at least %d runs but got %d", test.minRuns, len(runs))
+			}
+			if agg := aggregateRuns(runs); agg.Iterations != len(runs) {
+				t.Errorf("expected %d iterations but got %d", len(runs), agg.Iterations)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	thresholdFlag        = "threshold"
+	ratioThresholdFlag   = "ratio-threshold"
+	failOnRegressionFlag = "fail-on-regression"
+
+	defaultTimeThreshold  = 0.05
+	defaultRatioThreshold = 0.01
+
+	statusOk         = "ok"
+	statusImproved   = "improved"
+	statusRegression = "REGRESSION"
+	statusAdded      = "added"
+	statusRemoved    = "removed"
+)
+
+// CompareOptions controls what counts as a regression between two runs
+type CompareOptions struct {
+	// relative increase in compress or decompress time
+	TimeThreshold float64
+	// relative increase in compression ratio
+	RatioThreshold float64
+}
+
+// Delta is the change in one metric of a codec between two runs
+type Delta struct {
+	Old    float64
+	New    float64
+	Change float64
+	// whether the change is statistically significant, only meaningful if Tested is set
+	Significant bool
+	Tested      bool
+	Regression  bool
+	Improvement bool
+}
+
+// Comparison matches the results of a codec in a baseline run and a current run.
+// Baseline or Current is nil if the codec only appears in one of the runs
+type Comparison struct {
+	Name           string
+	Baseline       *BenchmarkResult
+	Current        *BenchmarkResult
+	CompressTime   *Delta
+	DecompressTime *Delta
+	Ratio          *Delta
+}
+
+func NewCompareCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compare <baseline> <current>",
+		Short: "Compares two saved benchmark results",
+		Long: `Loads two results saved with --save (or --output json), matches the
+codecs by name and reports the change in compress time, decompress time and
+compression ratio. Changes in time are tested for statistical significance when
+both runs were repeated with --count or --adaptive.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCompare(cmd, args[0], args[1])
+		},
+	}
+	cmd.Flags().Float64(thresholdFlag, defaultTimeThreshold, "Relative increase in compress or decompress time which counts as a regression")
+	cmd.Flags().Float64(ratioThresholdFlag, defaultRatioThreshold, "Relative increase in compression ratio which counts as a regression")
+	cmd.Flags().Bool(failOnRegressionFlag, false, "Exit with an error if any codec regressed")
+	return cmd
+}
+
+func runCompare(cmd *cobra.Command, baselineFile, currentFile string) error {
+	opts, err := getCompareFlags(cmd)
+	if err != nil {
+		return fmt.Errorf("error while preparing comparison: %v", err)
+	}
+	baseline, err := readReport(baselineFile)
+	if err != nil {
+		return fmt.Errorf("error while reading baseline: %v", err)
+	}
+	current, err := readReport(currentFile)
+	if err != nil {
+		return fmt.Errorf("error while reading current results: %v", err)
+	}
+	comparisons := compareReports(baseline, current, opts)
+	printComparisons(cmd.OutOrStdout(), baseline, current, comparisons)
+	fail, _ := cmd.Flags().GetBool(failOnRegressionFlag)
+	if n := countRegressions(comparisons); fail && n > 0 {
+		// the comparison has already been printed, the usage would only hide it
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d codecs regressed", n)
+	}
+	return nil
+}
+
+func getCompareFlags(cmd *cobra.Command) (*CompareOptions, error) {
+	timeThreshold, err := cmd.Flags().GetFloat64(thresholdFlag)
+	if err != nil {
+		return nil, err
+	}
+	if timeThreshold < 0 {
+		return nil, fmt.Errorf("invalid argument for %s: must not be negative", thresholdFlag)
+	}
+	ratioThreshold, err := cmd.Flags().GetFloat64(ratioThresholdFlag)
+	if err != nil {
+		return nil, err
+	}
+	if ratioThreshold < 0 {
+		return nil, fmt.Errorf("invalid argument for %s: must not be negative", ratioThresholdFlag)
+	}
+	return &CompareOptions{
+		TimeThreshold:  timeThreshold,
+		RatioThreshold: ratioThreshold,
+	}, nil
+}
+
+// matches the results of both reports by codec name, in the order of the baseline followed
+// by any codecs which are only in the current report
+func compareReports(baseline, current *Report, opts *CompareOptions) []*Comparison {
+	currentByName := map[string]*BenchmarkResult{}
+	for _, result := range current.Results {
+		currentByName[result.Name] = result
+	}
+	seen := map[string]bool{}
+	comparisons := []*Comparison{}
+	for _, old := range baseline.Results {
+		seen[old.Name] = true
+		comparisons = append(comparisons, compareResults(old.Name, old, currentByName[old.Name], opts))
+	}
+	for _, result := range current.Results {
+		if !seen[result.Name] {
+			comparisons = append(comparisons, compareResults(result.Name, nil, result, opts))
+		}
+	}
+	return comparisons
+}
+
+func compareResults(name string, old, new *BenchmarkResult, opts *CompareOptions) *Comparison {
+	comparison := &Comparison{
+		Name:     name,
+		Baseline: old,
+		Current:  new,
+	}
+	if old == nil || new == nil {
+		return comparison
+	}
+	comparison.CompressTime = compareTimes(old.CompressTime, new.CompressTime,
+		old.CompressStats, new.CompressStats, old.Iterations, new.Iterations, opts.TimeThreshold)
+	comparison.DecompressTime = compareTimes(old.DecompressTime, new.DecompressTime,
+		old.DecompressStats, new.DecompressStats, old.Iterations, new.Iterations, opts.TimeThreshold)
+	comparison.Ratio = newDelta(old.Ratio, new.Ratio, opts.RatioThreshold)
+	return comparison
+}
+
+// compares two times, which only count as a regression or improvement if the change is over
+// the threshold and, when both runs have enough iterations to tell, statistically significant.
+// Significance is tested on the means, so tested changes are between the means rather than the medians
+func compareTimes(old, new time.Duration, oldStats, newStats *TimingStats, oldN, newN int, threshold float64) *Delta {
+	if oldStats == nil || newStats == nil || oldN < 2 || newN < 2 {
+		return newDelta(float64(old), float64(new), threshold)
+	}
+	delta := newDelta(float64(oldStats.Mean), float64(newStats.Mean), threshold)
+	delta.Tested = true
+	delta.Significant = welchTTest(oldStats, newStats, oldN, newN)
+	if !delta.Significant {
+		delta.Regression = false
+		delta.Improvement = false
+	}
+	return delta
+}
+
+func newDelta(old, new, threshold float64) *Delta {
+	delta := &Delta{
+		Old: old,
+		New: new,
+	}
+	if old != 0 {
+		delta.Change = (new - old) / old
+	}
+	delta.Regression = delta.Change > threshold
+	delta.Improvement = delta.Change < -threshold
+	return delta
+}
+
+// returns whether the means of two sets of runs differ at the 95% level, without assuming
+// that both sets have the same variance
+func welchTTest(a, b *TimingStats, aN, bN int) bool {
+	aVar := math.Pow(float64(a.StdDev), 2) / float64(aN)
+	bVar := math.Pow(float64(b.StdDev), 2) / float64(bN)
+	diff := math.Abs(float64(b.Mean) - float64(a.Mean))
+	if aVar+bVar == 0 {
+		return diff > 0
+	}
+	t := diff / math.Sqrt(aVar+bVar)
+	// Welch–Satterthwaite equation, rounded down to the table of critical values. It is at least
+	// 1 with 2 runs or more, but rounding could take it to 0, which has no critical value
+	df := math.Pow(aVar+bVar, 2) / (aVar*aVar/float64(aN-1) + bVar*bVar/float64(bN-1))
+	return t > tCritical(max(1, int(df)))
+}
+
+func countRegressions(comparisons []*Comparison) int {
+	n := 0
+	for _, comparison := range comparisons {
+		if comparison.status() == statusRegression {
+			n++
+		}
+	}
+	return n
+}
+
+// returns a one word summary of the comparison
+func (c *Comparison) status() string {
+	switch {
+	case c.Baseline == nil:
+		return statusAdded
+	case c.Current == nil:
+		return statusRemoved
+	}
+	deltas := []*Delta{c.CompressTime, c.DecompressTime, c.Ratio}
+	improved := false
+	for _, delta := range deltas {
+		if delta.Regression {
+			return statusRegression
+		}
+		improved = improved || delta.Improvement
+	}
+	if improved {
+		return statusImproved
+	}
+	return statusOk
+}
+
+func printComparisons(w io.Writer, baseline, current *Report, comparisons []*Comparison) {
+	if baseline.Input.Size != current.Input.Size || baseline.Input.Source != current.Input.Source {
+		fmt.Fprintf(w, "Warning: the runs used different inputs (%s, %s vs %s, %s), the results may not be comparable\n",
+			baseline.Input.Source, formatBytes(baseline.Input.Size), current.Input.Source, formatBytes(current.Input.Size))
+	}
+	tested, untested := false, false
+	tw := tabwriter.NewWriter(w, 2, 2, 4, ' ', 0)
+	fmt.Fprintln(tw, strings.Join([]string{
+		"Compression-Library", "Compress-Time", "Change", "Decompress-Time", "Change", "Ratio", "Change", "Status",
+	}, "\t"))
+	for _, c := range comparisons {
+		if c.Baseline == nil || c.Current == nil {
+			fmt.Fprintf(tw, "%s\t\t\t\t\t\t\t%s\n", c.Name, c.status())
+			continue
+		}
+		tested = tested || c.CompressTime.Tested || c.DecompressTime.Tested
+		untested = untested || !c.CompressTime.Tested || !c.DecompressTime.Tested
+		fmt.Fprintln(tw, strings.Join([]string{
+			c.Name,
+			formatTimeChange(c.CompressTime),
+			formatChange(c.CompressTime),
+			formatTimeChange(c.DecompressTime),
+			formatChange(c.DecompressTime),
+			fmt.Sprintf("%s -> %s", formatRatio(c.Ratio.Old), formatRatio(c.Ratio.New)),
+			formatChange(c.Ratio),
+			c.status(),
+		}, "\t"))
+	}
+	tw.Flush()
+	if tested {
+		fmt.Fprintln(w, "Where significance was tested, times are the means of every run rather than the medians")
+	}
+	if untested {
+		fmt.Fprintln(w, "Significance was not tested where a run has fewer than 2 iterations, repeat runs with --count or --adaptive")
+	}
+}
+
+func formatTimeChange(delta *Delta) string {
+	return fmt.Sprintf("%s -> %s", time.Duration(delta.Old), time.Duration(delta.New))
+}
+
+// formats the relative change, marking changes which are too small to be told apart from noise
+func formatChange(delta *Delta) string {
+	change := fmt.Sprintf("%+.2f%%", delta.Change*100)
+	if delta.Tested && !delta.Significant {
+		change += " (noise)"
+	}
+	return change
+}
+
+// reads a report written by --save or --output json
+func readReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{}
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, fmt.Errorf("%s is not a JSON benchmark report: %v", path, err)
+	}
+	if report.Version == 0 {
+		return nil, fmt.Errorf("%s is not a JSON benchmark report", path)
+	}
+	if report.Version > reportVersion {
+		return nil, fmt.Errorf("%s was written by a newer version (%d) of the report format", path, report.Version)
+	}
+	return report, nil
+}
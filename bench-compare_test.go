@@ -0,0 +1,186 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWelchTTest(t *testing.T) {
+	type testData struct {
+		name string
+		a    *TimingStats
+		b    *TimingStats
+		n    int
+		exp  bool
+	}
+	tests := []testData{
+		{
+			name: "same mean",
+			a:    &TimingStats{Mean: 100, StdDev: 10},
+			b:    &TimingStats{Mean: 100, StdDev: 10},
+			n:    10,
+			exp:  false,
+		},
+		{
+			name: "difference within noise",
+			a:    &TimingStats{Mean: 100, StdDev: 20},
+			b:    &TimingStats{Mean: 105, StdDev: 20},
+			n:    10,
+			exp:  false,
+		},
+		{
+			name: "clear difference",
+			a:    &TimingStats{Mean: 100, StdDev: 2},
+			b:    &TimingStats{Mean: 110, StdDev: 2},
+			n:    10,
+			exp:  true,
+		},
+		{
+			name: "no variance",
+			a:    &TimingStats{Mean: 100},
+			b:    &TimingStats{Mean: 101},
+			n:    3,
+			exp:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if out := welchTTest(test.a, test.b, test.n, test.n); out != test.exp {
+				t.Errorf("expected %v but got %v", test.exp, out)
+			}
+		})
+	}
+}
+
+func TestCompareReports(t *testing.T) {
+	baseline := &Report{
+		Results: []*BenchmarkResult{
+			{Name: "gzip", CompressTime: 100, DecompressTime: 100, Ratio: 0.5},
+			{Name: "zlib", CompressTime: 100, DecompressTime: 100, Ratio: 0.5},
+			{Name: "zstd", CompressTime: 100, DecompressTime: 100, Ratio: 0.5},
+			{
+				Name: "s2", CompressTime: 100, DecompressTime: 100, Ratio: 0.5, Iterations: 10,
+				CompressStats:   &TimingStats{Mean: 100, StdDev: 50},
+				DecompressStats: &TimingStats{Mean: 100, StdDev: 50},
+			},
+			{Name: "flate", CompressTime: 100, DecompressTime: 100, Ratio: 0.5},
+		},
+	}
+	current := &Report{
+		Results: []*BenchmarkResult{
+			{Name: "gzip", CompressTime: 104, DecompressTime: 90, Ratio: 0.5},
+			{Name: "zlib", CompressTime: 100, DecompressTime: 200, Ratio: 0.5},
+			{Name: "zstd", CompressTime: 100, DecompressTime: 100, Ratio: 0.6},
+			{
+				Name: "s2", CompressTime: 120, DecompressTime: 120, Ratio: 0.5, Iterations: 10,
+				CompressStats:   &TimingStats{Mean: 120, StdDev: 50},
+				DecompressStats: &TimingStats{Mean: 120, StdDev: 50},
+			},
+			{Name: "snappy", CompressTime: 100, DecompressTime: 100, Ratio: 0.5},
+		},
+	}
+	expStatuses := map[string]string{
+		"gzip":   statusImproved,
+		"zlib":   statusRegression,
+		"zstd":   statusRegression,
+		"s2":     statusOk,
+		"flate":  statusRemoved,
+		"snappy": statusAdded,
+	}
+	opts := &CompareOptions{TimeThreshold: defaultTimeThreshold, RatioThreshold: defaultRatioThreshold}
+	comparisons := compareReports(baseline, current, opts)
+	if len(comparisons) != len(expStatuses) {
+		t.Fatalf("expected %d comparisons but got %d", len(expStatuses), len(comparisons))
+	}
+	for _, comparison := range comparisons {
+		if out := comparison.status(); out != expStatuses[comparison.Name] {
+			t.Errorf("expected %s to be %s but got %s", comparison.Name, expStatuses[comparison.Name], out)
+		}
+	}
+	if n := countRegressions(comparisons); n != 2 {
+		t.Errorf("expected 2 regressions but got %d", n)
+	}
+}
+
+func TestCompareCmd(t *testing.T) {
+	dir := t.TempDir()
+	baseline := filepath.Join(dir, "baseline.json")
+	current := filepath.Join(dir, "current.json")
+	report := getTestReport()
+	if err := saveReport(baseline, report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	report.Results[0].CompressTime *= 2
+	if err := saveReport(current, report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	type testData struct {
+		name    string
+		args    []string
+		wantErr bool
+	}
+	tests := []testData{
+		{
+			name: "compare",
+			args: []string{"compare", baseline, current},
+		},
+		{
+			name: "no regression against itself",
+			args: []string{"compare", baseline, baseline, "--fail-on-regression"},
+		},
+		{
+			name:    "fail on regression",
+			args:    []string{"compare", baseline, current, "--fail-on-regression"},
+			wantErr: true,
+		},
+		{
+			name:    "missing file",
+			args:    []string{"compare", baseline, filepath.Join(dir, "missing.json")},
+			wantErr: true,
+		},
+		{
+			name:    "not a report",
+			args:    []string{"compare", baseline, "./bench_test.go"},
+			wantErr: true,
+		},
+		{
+			name:    "one file",
+			args:    []string{"compare", baseline},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := NewBenchCmd()
+			cmd.SetArgs(test.args)
+			err := cmd.Execute()
+			if test.wantErr && err == nil {
+				t.Errorf("expected error, but did not get one")
+			}
+			if !test.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCompareTimesUntested(t *testing.T) {
+	delta := compareTimes(100*time.Millisecond, 200*time.Millisecond, nil, nil, 1, 1, defaultTimeThreshold)
+	if delta.Tested || !delta.Regression {
+		t.Errorf("expected an untested regression but got %+v", delta)
+	}
+}
+
+func TestCompareTimesMeans(t *testing.T) {
+	// the medians are the same, but a few slow runs moved the mean, which is what is tested
+	oldStats := &TimingStats{Mean: 100 * time.Millisecond, StdDev: time.Millisecond}
+	newStats := &TimingStats{Mean: 150 * time.Millisecond, StdDev: time.Millisecond}
+	delta := compareTimes(100*time.Millisecond, 100*time.Millisecond, oldStats, newStats, 10, 10, defaultTimeThreshold)
+	if !delta.Tested || !delta.Significant || !delta.Regression {
+		t.Errorf("expected a significant regression but got %+v", delta)
+	}
+	if delta.Old != float64(oldStats.Mean) || delta.New != float64(newStats.Mean) || delta.Change != 0.5 {
+		t.Errorf("expected the change between the means but got %+v", delta)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ConcurrencyOptions control how many workers run each codec at the same time
+type ConcurrencyOptions struct {
+	// every number of workers to benchmark each codec with
+	Workers []int
+	// give each worker a different input instead of sharing one
+	Distinct bool
+	// if set, returns the inputs of the given number of workers after the first when the inputs are
+	// distinct, otherwise those workers compress the shared input rotated to start at a different
+	// offset. Every codec must get the same inputs for the same run, so that they can be compared
+	WorkerInputs func(n int) ([][]byte, error)
+}
+
+// ConcurrencyStats summarizes a benchmark in which several workers ran the same codec at the same time
+type ConcurrencyStats struct {
+	Codec   string `json:"codec"`
+	Workers int    `json:"workers"`
+	// time from starting the workers until the last one finished
+	WallTime time.Duration `json:"wall_time_ns"`
+	// MB/s of uncompressed data compressed and decompressed by all workers together
+	Throughput float64       `json:"throughput_mb_per_sec"`
+	CPUTime    time.Duration `json:"cpu_time_ns"`
+	// throughput relative to a single worker, only set when sweeping the number of workers
+	Scaling float64 `json:"scaling,omitempty"`
+}
+
+// ConcurrentBenchmarker implements the Benchmarker interface by running several workers
+// at once, each compressing and decompressing the whole input
+type ConcurrentBenchmarker struct {
+	workers      []Benchmarker
+	distinct     bool
+	workerInputs func(n int) ([][]byte, error)
+}
+
+// creates a worker for each of n goroutines, benchmarkers which keep state between payloads
+// are created again for every worker. The number of workers in opts is ignored
+func NewConcurrentBenchmarker(benchmarker Benchmarker, n int, opts *ConcurrencyOptions) (*ConcurrentBenchmarker, error) {
+	workers := make([]Benchmarker, n)
+	for i := range workers {
+		worker, err := newWorker(benchmarker)
+		if err != nil {
+			return nil, err
+		}
+		workers[i] = worker
+	}
+	return &ConcurrentBenchmarker{
+		workers:      workers,
+		distinct:     opts.Distinct,
+		workerInputs: opts.WorkerInputs,
+	}, nil
+}
+
+func newWorker(benchmarker Benchmarker) (Benchmarker, error) {
+	if reusing, ok := benchmarker.(*ReusingBenchmarker); ok {
+		return NewReusingBenchmarker(reusing.base)
+	}
+	return benchmarker, nil
+}
+
+// closes the Benchmarker of every worker
+func (cb *ConcurrentBenchmarker) Close() {
+	closeBenchmarkers(cb.workers)
+}
+
+func (cb *ConcurrentBenchmarker) RunBenchmark(input []byte, opts *RunOptions) (*BenchmarkResult, error) {
+	n := len(cb.workers)
+	inputs, err := cb.getWorkerInputs(input)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*BenchmarkResult, n)
+	errs := make([]error, n)
+	var ready, done sync.WaitGroup
+	start := make(chan struct{})
+	ready.Add(n)
+	done.Add(n)
+	for i, worker := range cb.workers {
+		go func() {
+			defer done.Done()
+			ready.Done()
+			<-start
+			results[i], errs[i] = worker.RunBenchmark(inputs[i], opts)
+		}()
+	}
+	// every worker is waiting, so they all start at the same time
+	ready.Wait()
+	cpu0 := processCPUTime()
+	t0 := time.Now()
+	close(start)
+	done.Wait()
+	wallTime := time.Since(t0)
+	cpuTime := processCPUTime() - cpu0
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return combineWorkerResults(results, wallTime, cpuTime), nil
+}
+
+// returns the input of every worker. Unless the workers share the input, every worker after the
+// first gets its own generated input, or the input rotated to start at a different offset, so that
+// no two workers compress the same bytes
+func (cb *ConcurrentBenchmarker) getWorkerInputs(input []byte) ([][]byte, error) {
+	n := len(cb.workers)
+	if cb.distinct && cb.workerInputs != nil {
+		generated, err := cb.workerInputs(n - 1)
+		if err != nil {
+			return nil, err
+		}
+		return append([][]byte{input}, generated...), nil
+	}
+	inputs := make([][]byte, n)
+	for i := range inputs {
+		if !cb.distinct || i == 0 {
+			inputs[i] = input
+		} else {
+			inputs[i] = rotateInput(input, i*len(input)/n)
+		}
+	}
+	return inputs, nil
+}
+
+// returns a copy of the input which starts at the offset and wraps around to its start
+func rotateInput(input []byte, offset int) []byte {
+	rotated := make([]byte, 0, len(input))
+	rotated = append(rotated, input[offset:]...)
+	return append(rotated, input[:offset]...)
+}
+
+// combines the results of workers which ran at the same time. Times are the median time of a
+// single worker, while sizes and speeds are totals across all workers
+func combineWorkerResults(results []*BenchmarkResult, wallTime, cpuTime time.Duration) *BenchmarkResult {
+	n := len(results)
+	compTimes := make([]time.Duration, n)
+	decompTimes := make([]time.Duration, n)
+	combined := &BenchmarkResult{
+		Name: fmt.Sprintf("%s/%d", results[0].Name, n),
+	}
+	for i, result := range results {
+		compTimes[i] = result.CompressTime
+		decompTimes[i] = result.DecompressTime
+		combined.InputSize += result.InputSize
+		combined.CompressedSize += result.CompressedSize
+		combined.SetupTime = max(combined.SetupTime, result.SetupTime)
+	}
+	combined.CompressTime = findMedianTime(compTimes)
+	combined.DecompressTime = findMedianTime(decompTimes)
+	if combined.InputSize > 0 {
+		combined.Ratio = float64(combined.CompressedSize) / float64(combined.InputSize)
+	}
+	combined.setThroughput()
+	combined.Concurrency = &ConcurrencyStats{
+		Codec:      results[0].Name,
+		Workers:    n,
+		WallTime:   wallTime,
+		Throughput: throughput(combined.InputSize, wallTime),
+		CPUTime:    cpuTime,
+	}
+	return combined
+}
+
+// wraps every Benchmarker once for each number of workers
+func newConcurrentBenchmarkers(benchmarkers []Benchmarker, opts *ConcurrencyOptions) ([]Benchmarker, error) {
+	out := make([]Benchmarker, 0, len(benchmarkers)*len(opts.Workers))
+	for _, benchmarker := range benchmarkers {
+		for _, n := range opts.Workers {
+			concurrent, err := NewConcurrentBenchmarker(benchmarker, n, opts)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, concurrent)
+		}
+		// every worker has its own encoder and decoder, so those of the wrapped Benchmarker are unused
+		closeBenchmarkers([]Benchmarker{benchmarker})
+	}
+	return out, nil
+}
+
+// returns every number of workers from 1 to the number of usable cores
+func getWorkerSweep() []int {
+	workers := make([]int, runtime.GOMAXPROCS(0))
+	for i := range workers {
+		workers[i] = i + 1
+	}
+	return workers
+}
+
+// returns the average number of cores kept busy by all workers
+func (cs *ConcurrencyStats) getCPUUtilization() float64 {
+	if cs.WallTime <= 0 {
+		return 0
+	}
+	return float64(cs.CPUTime) / float64(cs.WallTime)
+}
+
+// sets the scaling of each result relative to the result of the same codec with a single worker
+func setConcurrencyScaling(results []*BenchmarkResult) {
+	single := map[string]*ConcurrencyStats{}
+	for _, result := range results {
+		if result.Concurrency != nil && result.Concurrency.Workers == 1 {
+			single[result.Concurrency.Codec] = result.Concurrency
+		}
+	}
+	for _, result := range results {
+		if result.Concurrency == nil {
+			continue
+		}
+		base, ok := single[result.Concurrency.Codec]
+		if ok && base.Throughput > 0 {
+			result.Concurrency.Scaling = result.Concurrency.Throughput / base.Throughput
+		}
+	}
+}
+
+// combines the concurrency stats of repeated runs of the same codec by taking the median of each value
+func aggregateConcurrencyStats(stats []*ConcurrencyStats) *ConcurrencyStats {
+	if len(stats) == 0 || stats[0] == nil {
+		return nil
+	}
+	n := len(stats)
+	wallTimes := make([]time.Duration, n)
+	throughputs := make([]float64, n)
+	cpuTimes := make([]time.Duration, n)
+	for i, stat := range stats {
+		wallTimes[i] = stat.WallTime
+		throughputs[i] = stat.Throughput
+		cpuTimes[i] = stat.CPUTime
+	}
+	return &ConcurrencyStats{
+		Codec:      stats[0].Codec,
+		Workers:    stats[0].Workers,
+		WallTime:   findMedianTime(wallTimes),
+		Throughput: findMedianFloat64(throughputs),
+		CPUTime:    findMedianTime(cpuTimes),
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestConcurrentBenchmarker(t *testing.T) {
+	input := []byte("the quick brown fox jumps over the lazy dog, the quick brown fox jumps over the lazy dog")
+	for _, distinct := range []bool{false, true} {
+		benchmarker, err := NewConcurrentBenchmarker(NewS2Runner(s2LevelDefault), 3, &ConcurrencyOptions{Distinct: distinct})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		result, err := benchmarker.RunBenchmark(input, NewRunOptions())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Name != "s2-1/3" {
+			t.Errorf("expected name s2-1/3 but got %s", result.Name)
+		}
+		if result.InputSize != 3*len(input) {
+			t.Errorf("expected input size %d but got %d", 3*len(input), result.InputSize)
+		}
+		if result.Concurrency == nil || result.Concurrency.Workers != 3 || result.Concurrency.Codec != "s2-1" {
+			t.Fatalf("unexpected concurrency stats %+v", result.Concurrency)
+		}
+		if result.Concurrency.WallTime <= 0 || result.Concurrency.Throughput <= 0 {
+			t.Errorf("expected wall time and throughput to be set but got %+v", result.Concurrency)
+		}
+	}
+}
+
+func TestConcurrentReusingWorkers(t *testing.T) {
+	reusing, err := NewReusingBenchmarker(NewZstdRunner(zstd.SpeedFastest, nil, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	benchmarker, err := NewConcurrentBenchmarker(reusing, 2, &ConcurrencyOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if benchmarker.workers[0] == benchmarker.workers[1] {
+		t.Errorf("expected each worker to have its own reusable codec")
+	}
+	if _, err := benchmarker.RunBenchmark([]byte("test input"), NewRunOptions()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestConcurrentWorkerInputs(t *testing.T) {
+	input := []byte("0123456789ab")
+	shared, err := NewConcurrentBenchmarker(NewS2Runner(s2LevelDefault), 3, &ConcurrencyOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	inputs, err := shared.getWorkerInputs(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, workerInput := range inputs {
+		if string(workerInput) != string(input) {
+			t.Errorf("expected worker %d to share the input but got %s", i, workerInput)
+		}
+	}
+	rotated, err := NewConcurrentBenchmarker(NewS2Runner(s2LevelDefault), 3, &ConcurrencyOptions{Distinct: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	inputs, err = rotated.getWorkerInputs(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expInputs := []string{"0123456789ab", "456789ab0123", "89ab01234567"}
+	for i, workerInput := range inputs {
+		if string(workerInput) != expInputs[i] {
+			t.Errorf("expected worker %d to get %s but got %s", i, expInputs[i], workerInput)
+		}
+	}
+	opts := &ConcurrencyOptions{
+		Distinct: true,
+		WorkerInputs: func(n int) ([][]byte, error) {
+			generated := make([][]byte, n)
+			for i := range generated {
+				generated[i] = []byte{byte(i + 1)}
+			}
+			return generated, nil
+		},
+	}
+	generating, err := NewConcurrentBenchmarker(NewS2Runner(s2LevelDefault), 3, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	inputs, err = generating.getWorkerInputs(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(inputs) != 3 || string(inputs[0]) != string(input) || inputs[1][0] != 1 || inputs[2][0] != 2 {
+		t.Errorf("expected every worker after the first to get a generated input but got %v", inputs)
+	}
+}
+
+func TestSetConcurrencyScaling(t *testing.T) {
+	results := []*BenchmarkResult{
+		{Name: "s2/1", Concurrency: &ConcurrencyStats{Codec: "s2", Workers: 1, Throughput: 100}},
+		{Name: "s2/2", Concurrency: &ConcurrencyStats{Codec: "s2", Workers: 2, Throughput: 180}},
+		{Name: "zstd/2", Concurrency: &ConcurrencyStats{Codec: "zstd", Workers: 2, Throughput: 50}},
+		{Name: "gzip"},
+	}
+	setConcurrencyScaling(results)
+	expScaling := []float64{1, 1.8, 0}
+	for i, exp := range expScaling {
+		if out := results[i].Concurrency.Scaling; out != exp {
+			t.Errorf("expected scaling of %s to be %v but got %v", results[i].Name, exp, out)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// extension group of files which have no extension
+const noExtension = "(none)"
+
+// CorpusOptions select the files of a corpus
+type CorpusOptions struct {
+	// directory to benchmark every file of, or empty when using Glob
+	Dir       string
+	Glob      string
+	Recursive bool
+	// files smaller or larger than these are skipped, a zero max means no limit
+	MinFileSize int64
+	MaxFileSize int64
+	// files beyond this total size are skipped, zero means no limit
+	MaxTotalSize int64
+}
+
+// corpusFile is a single file of the corpus, read into memory
+type corpusFile struct {
+	path  string
+	ext   string
+	input []byte
+}
+
+// CorpusReport holds the results of every file and every extension of a corpus, while the
+// results of the whole corpus are the results of the report
+type CorpusReport struct {
+	Files      []*CorpusEntry `json:"files"`
+	Extensions []*CorpusEntry `json:"extensions"`
+}
+
+// CorpusEntry holds the results of one file, or the combined results of a group of files
+type CorpusEntry struct {
+	// file path or extension
+	Name    string             `json:"name"`
+	Files   int                `json:"files"`
+	Size    int                `json:"size"`
+	Results []*BenchmarkResult `json:"results"`
+}
+
+// finds and reads the files of the corpus, sorted by path
+func readCorpus(opts *CorpusOptions) ([]*corpusFile, error) {
+	paths, err := findCorpusFiles(opts)
+	if err != nil {
+		return nil, err
+	}
+	var files []*corpusFile
+	var totalSize int64
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		size := info.Size()
+		if !info.Mode().IsRegular() || size == 0 || size < opts.MinFileSize || (opts.MaxFileSize > 0 && size > opts.MaxFileSize) {
+			continue
+		}
+		if opts.MaxTotalSize > 0 && totalSize+size > opts.MaxTotalSize {
+			continue
+		}
+		input, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading input file: %v", err)
+		}
+		totalSize += size
+		files = append(files, &corpusFile{
+			path:  path,
+			ext:   corpusExtension(path),
+			input: input,
+		})
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files of the corpus are within the size limits")
+	}
+	return files, nil
+}
+
+// returns the paths matching the glob, or the paths of the files in the directory
+func findCorpusFiles(opts *CorpusOptions) ([]string, error) {
+	if opts.Glob != "" {
+		paths, err := filepath.Glob(opts.Glob)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument for %s: %v", globFlag, err)
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("invalid argument for %s: no files match '%s'", globFlag, opts.Glob)
+		}
+		return paths, nil
+	}
+	var paths []string
+	err := filepath.WalkDir(opts.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != opts.Dir && !opts.Recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while reading input directory: %v", err)
+	}
+	return paths, nil
+}
+
+// returns the lowercase extension of the path, which files are grouped by
+func corpusExtension(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return noExtension
+	}
+	return ext
+}
+
+// benchmarks every codec on every file of the corpus, repeating each run count times after warmup
+// discarded runs. Returns the total size of the corpus, the combined results of the whole corpus,
+// and the results of each file and extension
+func runCorpusBenchmark(benchmarkers []Benchmarker, files []*corpusFile, count, warmup int, opts *RunOptions) (int, []*BenchmarkResult, *CorpusReport, error) {
+	// the results of each file, by run then codec
+	fileResults := make([][][]*BenchmarkResult, len(files))
+	for run := range warmup + count {
+		for i, file := range files {
+			results := make([]*BenchmarkResult, len(benchmarkers))
+			for j, benchmarker := range benchmarkers {
+				result, err := benchmarker.RunBenchmark(file.input, opts)
+				if err != nil {
+					return 0, nil, nil, fmt.Errorf("error while running benchmark on %s: %v", file.path, err)
+				}
+				results[j] = result
+			}
+			if run < warmup {
+				continue
+			}
+			fileResults[i] = append(fileResults[i], results)
+		}
+	}
+
+	corpus := &CorpusReport{}
+	extFiles := map[string][]int{}
+	totalSize := 0
+	for i, file := range files {
+		corpus.Files = append(corpus.Files, &CorpusEntry{
+			Name:    file.path,
+			Files:   1,
+			Size:    len(file.input),
+			Results: aggregateResults(fileResults[i]),
+		})
+		extFiles[file.ext] = append(extFiles[file.ext], i)
+		totalSize += len(file.input)
+	}
+	exts := make([]string, 0, len(extFiles))
+	for ext := range extFiles {
+		exts = append(exts, ext)
+	}
+	slices.Sort(exts)
+	for _, ext := range exts {
+		corpus.Extensions = append(corpus.Extensions, newCorpusGroup(ext, files, fileResults, extFiles[ext]))
+	}
+	all := make([]int, len(files))
+	for i := range all {
+		all[i] = i
+	}
+	return totalSize, newCorpusGroup("", files, fileResults, all).Results, corpus, nil
+}
+
+// combines the results of the files at the given indexes, run by run, then aggregates the runs
+func newCorpusGroup(name string, files []*corpusFile, fileResults [][][]*BenchmarkResult, indexes []int) *CorpusEntry {
+	entry := &CorpusEntry{
+		Name:  name,
+		Files: len(indexes),
+	}
+	for _, i := range indexes {
+		entry.Size += len(files[i].input)
+	}
+	runs := len(fileResults[indexes[0]])
+	nResults := make([][]*BenchmarkResult, runs)
+	for run := range runs {
+		codecs := len(fileResults[indexes[0]][run])
+		nResults[run] = make([]*BenchmarkResult, codecs)
+		for codec := range codecs {
+			results := make([]*BenchmarkResult, len(indexes))
+			for j, i := range indexes {
+				results[j] = fileResults[i][run][codec]
+			}
+			nResults[run][codec] = sumResults(results)
+		}
+	}
+	entry.Results = aggregateResults(nResults)
+	return entry
+}
+
+// combines the results of a codec on several inputs. Times and sizes are totals across all
+// inputs, and the ratio is the ratio of the totals
+func sumResults(results []*BenchmarkResult) *BenchmarkResult {
+	total := &BenchmarkResult{
+		Name:      results[0].Name,
+		SetupTime: results[0].SetupTime,
+	}
+	for _, result := range results {
+		total.CompressTime += result.CompressTime
+		total.DecompressTime += result.DecompressTime
+		total.CompressCPUTime += result.CompressCPUTime
+		total.DecompressCPUTime += result.DecompressCPUTime
+		total.InputSize += result.InputSize
+		total.CompressedSize += result.CompressedSize
+		total.CompressMemory = addMemoryStats(total.CompressMemory, result.CompressMemory)
+		total.DecompressMemory = addMemoryStats(total.DecompressMemory, result.DecompressMemory)
+	}
+	if total.InputSize > 0 {
+		total.Ratio = float64(total.CompressedSize) / float64(total.InputSize)
+	}
+	total.setThroughput()
+	return total
+}
+
+// sorts the results of every file and extension of the corpus
+func sortCorpusResults(corpus *CorpusReport, sortBy string) {
+	for _, entry := range slices.Concat(corpus.Files, corpus.Extensions) {
+		sortResults(entry.Results, sortBy)
+	}
+}
+
+// returns the directory or glob of the corpus, or an empty string if the input is not a corpus
+func getCorpusSource(cmd *cobra.Command) string {
+	if dir, _ := cmd.Flags().GetString(dirFlag); dir != "" {
+		return dir
+	}
+	glob, _ := cmd.Flags().GetString(globFlag)
+	return glob
+}
+
+// prints the results of every extension, and of every file if the user asked for them, with each
+// row prefixed by the file or extension it belongs to. The results of the whole corpus come after
+func printCorpusTables(w io.Writer, corpus *CorpusReport, fields []string, printers []func(*BenchmarkResult) string,
+	opts *PrintOptions, printTable func(io.Writer, []string, [][]string)) {
+	if opts.ShowFiles {
+		fmt.Fprintln(w, "\nPer file:")
+		printTable(w, append([]string{"File", "Size"}, fields...), getCorpusRows(corpus.Files, printers, false))
+	}
+	fmt.Fprintln(w, "\nPer extension:")
+	printTable(w, append([]string{"Extension", "Files", "Size"}, fields...), getCorpusRows(corpus.Extensions, printers, true))
+	fmt.Fprintln(w, "\nAll files:")
+}
+
+// formats the results of every entry into rows prefixed by the entry name, number of files and size
+func getCorpusRows(entries []*CorpusEntry, printers []func(*BenchmarkResult) string, showCount bool) [][]string {
+	var rows [][]string
+	for _, entry := range entries {
+		prefix := []string{entry.Name}
+		if showCount {
+			prefix = append(prefix, strconv.Itoa(entry.Files))
+		}
+		prefix = append(prefix, formatBytes(entry.Size))
+		for _, row := range getResultRows(entry.Results, printers) {
+			rows = append(rows, slices.Concat(prefix, row))
+		}
+	}
+	return rows
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// creates a corpus of files in a temporary directory, returning the directory
+func writeTestCorpus(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]int{
+		"a.json":        100,
+		"b.json":        2000,
+		"c.JSON":        50,
+		"notes":         300,
+		"empty.txt":     0,
+		"sub/d.json":    400,
+		"sub/deep/e.md": 500,
+	}
+	for name, size := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReadCorpus(t *testing.T) {
+	dir := writeTestCorpus(t)
+	tests := []struct {
+		name     string
+		opts     CorpusOptions
+		expFiles []string
+		wantErr  bool
+	}{
+		{
+			name:     "directory",
+			opts:     CorpusOptions{Dir: dir},
+			expFiles: []string{"a.json", "b.json", "c.JSON", "notes"},
+		},
+		{
+			name:     "recursive",
+			opts:     CorpusOptions{Dir: dir, Recursive: true},
+			expFiles: []string{"a.json", "b.json", "c.JSON", "notes", "sub/d.json", "sub/deep/e.md"},
+		},
+		{
+			name:     "glob",
+			opts:     CorpusOptions{Glob: filepath.Join(dir, "*.json")},
+			expFiles: []string{"a.json", "b.json"},
+		},
+		{
+			name:     "file size limits",
+			opts:     CorpusOptions{Dir: dir, Recursive: true, MinFileSize: 100, MaxFileSize: 400},
+			expFiles: []string{"a.json", "notes", "sub/d.json"},
+		},
+		{
+			name:     "total size limit",
+			opts:     CorpusOptions{Dir: dir, MaxTotalSize: 500},
+			expFiles: []string{"a.json", "c.JSON", "notes"},
+		},
+		{
+			name:    "nothing within limits",
+			opts:    CorpusOptions{Dir: dir, MinFileSize: 10000},
+			wantErr: true,
+		},
+		{
+			name:    "glob without matches",
+			opts:    CorpusOptions{Glob: filepath.Join(dir, "*.xml")},
+			wantErr: true,
+		},
+		{
+			name:    "missing directory",
+			opts:    CorpusOptions{Dir: filepath.Join(dir, "missing")},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files, err := readCorpus(&test.opts)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected error, but did not get one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			paths := make([]string, len(files))
+			for i, file := range files {
+				paths[i], _ = filepath.Rel(dir, file.path)
+				paths[i] = filepath.ToSlash(paths[i])
+			}
+			if !slices.Equal(paths, test.expFiles) {
+				t.Errorf("expected files %v, got %v", test.expFiles, paths)
+			}
+		})
+	}
+}
+
+func TestRunCorpusBenchmark(t *testing.T) {
+	files, err := readCorpus(&CorpusOptions{Dir: writeTestCorpus(t), Recursive: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	benchmarkers := []Benchmarker{NewDeflater(stdGzip, 6), NewS2Runner(s2LevelDefault)}
+	size, results, corpus, err := runCorpusBenchmark(benchmarkers, files, 2, 1, NewRunOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if size != 3350 {
+		t.Errorf("expected corpus size 3350, got %d", size)
+	}
+	if len(corpus.Files) != len(files) {
+		t.Errorf("expected %d file entries, got %d", len(files), len(corpus.Files))
+	}
+	exts := []string{}
+	for _, entry := range corpus.Extensions {
+		exts = append(exts, entry.Name)
+	}
+	if exp := []string{noExtension, ".json", ".md"}; !slices.Equal(exts, exp) {
+		t.Errorf("expected extensions %v, got %v", exp, exts)
+	}
+	for i, result := range results {
+		// the whole corpus is the sum of its extensions, and of its files
+		extSize, fileSize := 0, 0
+		for _, entry := range corpus.Extensions {
+			extSize += entry.Results[i].CompressedSize
+		}
+		for _, entry := range corpus.Files {
+			fileSize += entry.Results[i].CompressedSize
+		}
+		if result.InputSize != size || result.CompressedSize != extSize || result.CompressedSize != fileSize {
+			t.Errorf("%s: expected input size %d and compressed size %d from extensions and %d from files, got %d and %d",
+				result.Name, size, extSize, fileSize, result.InputSize, result.CompressedSize)
+		}
+		if result.Iterations != 2 {
+			t.Errorf("%s: expected 2 iterations, got %d", result.Name, result.Iterations)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "time"
+
+const cpuTimeSupported = false
+
+func processCPUTime() time.Duration {
+	return 0
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"fmt"
+	"math"
+	"math/rand"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -21,25 +24,89 @@ const (
 	randJsonStrLenRangeFlag    = "json-str-len-range"
 	randJsonDictFile           = "json-dict-file"
 	randJsonDictSize           = "json-dict-size"
+	seedFlag                   = "seed"
+	randJsonTypesFlag          = "json-types"
+	randJsonArrayLenRangeFlag  = "json-array-len-range"
+	randJsonSchemaFlag         = "json-schema"
+	randJsonExampleFlag        = "json-example"
+	randJsonRecordsFlag        = "json-records"
+	randJsonRecordsSizeFlag    = "json-records-size"
+	randJsonRecordsFormatFlag  = "json-records-format"
+	targetSizeFlag             = "target-size"
+	randJsonMaxSizeFlag        = "json-max-size"
 
 	// file input
 	fileInputFlag      = "file"
 	fileInputFlagShort = "f"
+	// value of the file flag which reads the input from stdin
+	stdinFile = "-"
+
+	// corpus input
+	dirFlag           = "dir"
+	globFlag          = "glob"
+	recursiveFlag     = "recursive"
+	minFileSizeFlag   = "min-file-size"
+	maxFileSizeFlag   = "max-file-size"
+	maxCorpusSizeFlag = "max-corpus-size"
+	showFilesFlag     = "show-files"
+
+	// codec selection
+	codecsFlag = "codecs"
+	levelsFlag = "levels"
+
+	// zstd dictionary training
+	zstdDictSamplesFlag = "zstd-dict-samples"
+	zstdDictFilesFlag   = "zstd-dict-files"
+	zstdDictSizeFlag    = "zstd-dict-size"
+
+	// encoder reuse
+	reuseFlag = "reuse"
+
+	// streaming
+	streamFlag      = "stream"
+	streamChunkFlag = "stream-chunk"
+	streamSizeFlag  = "stream-size"
+
+	// concurrency
+	concurrencyFlag      = "concurrency"
+	concurrencySweepFlag = "concurrency-sweep"
+	distinctInputsFlag   = "distinct-inputs"
+
+	// per-message benchmarking
+	messagesFlag     = "messages"
+	messageCountFlag = "message-count"
+
+	// output
+	outputFlag      = "output"
+	outputFlagShort = "o"
+	outFileFlag     = "out"
+	saveFlag        = "save"
+	sortFlag        = "sort"
 
 	// optional stats
 	networkSpeedFlag    = "network-bandwidth"
 	networkPayloadsFlag = "network-payloads"
 	printCTimeFlag      = "show-compress-time"
 	printDTimeFlag      = "show-decompress-time"
+	throughputFlag      = "show-throughput"
+	memoryFlag          = "show-memory"
+	cpuTimeFlag         = "show-cpu-time"
 	printJsonFlag       = "show-input"
 	countFlag           = "count"
 	countFlagShort      = "c"
+	warmupFlag          = "warmup"
+	adaptiveFlag        = "adaptive"
+	adaptiveTimeFlag    = "adaptive-time"
+	adaptiveErrorFlag   = "adaptive-error"
+	statsFlag           = "stats"
 
 	// default values
 	defaultFieldNum   = 3
 	defaultMaxDepth   = 5
 	defaultDegree     = 4
 	defaultJsonStrLen = 16
+	// a limit well above useful inputs, which stops parameters that grow the tree exponentially from exhausting memory
+	defaultJsonMaxSize = "1GB"
 )
 
 func getPrintOptions(cmd *cobra.Command) (*PrintOptions, error) {
@@ -50,6 +117,28 @@ func getPrintOptions(cmd *cobra.Command) (*PrintOptions, error) {
 	shouldPrint, _ := cmd.Flags().GetBool(printJsonFlag)
 	shouldPrintCTime, _ := cmd.Flags().GetBool(printCTimeFlag)
 	shouldPrintDTime, _ := cmd.Flags().GetBool(printDTimeFlag)
+	showThroughput, _ := cmd.Flags().GetBool(throughputFlag)
+	showMemory, _ := cmd.Flags().GetBool(memoryFlag)
+	showCPUTime, _ := cmd.Flags().GetBool(cpuTimeFlag)
+	workers, _ := cmd.Flags().GetInt(concurrencyFlag)
+	sweep, _ := cmd.Flags().GetBool(concurrencySweepFlag)
+	if showCPUTime && !cpuTimeSupported {
+		return nil, fmt.Errorf("%s is not supported on this platform", cpuTimeFlag)
+	}
+	// CPU time is measured for the whole process, so it would include the heap sampler of the memory meter
+	if showCPUTime && showMemory {
+		return nil, fmt.Errorf("--%s cannot be used with --%s", cpuTimeFlag, memoryFlag)
+	}
+	messageMode, _ := cmd.Flags().GetString(messagesFlag)
+	reuse, _ := cmd.Flags().GetBool(reuseFlag)
+	stream, _ := cmd.Flags().GetBool(streamFlag)
+	adaptive, _ := cmd.Flags().GetBool(adaptiveFlag)
+	showFiles, _ := cmd.Flags().GetBool(showFilesFlag)
+	statsStr, _ := cmd.Flags().GetString(statsFlag)
+	stats, err := parseStats(statsStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid argument for %s: %v", statsFlag, err)
+	}
 	numPayloads := 1
 	if numPayloadsInput, err := cmd.Flags().GetInt(networkPayloadsFlag); err == nil && numPayloadsInput != 0 {
 		numPayloads = numPayloadsInput
@@ -60,6 +149,16 @@ func getPrintOptions(cmd *cobra.Command) (*PrintOptions, error) {
 		NetworkPayloads:  numPayloads,
 		ShouldPrintCTime: shouldPrintCTime,
 		ShouldPrintDTime: shouldPrintDTime,
+		ShowThroughput:   showThroughput,
+		ShowMemory:       showMemory,
+		ShowCPUTime:      showCPUTime,
+		ShowConcurrency:  workers > 1 || sweep,
+		ShowScaling:      sweep,
+		ShowMessages:     messageMode != "",
+		ShowSetupTime:    reuse || stream,
+		Stats:            stats,
+		ShowIterations:   adaptive,
+		ShowFiles:        showFiles,
 	}, nil
 }
 
@@ -74,6 +173,286 @@ func getCountFlag(cmd *cobra.Command) (int, error) {
 	return count, nil
 }
 
+func getCodecsFlag(cmd *cobra.Command) ([]CodecSpec, error) {
+	codecsStr, err := cmd.Flags().GetString(codecsFlag)
+	if err != nil {
+		return nil, err
+	}
+	if codecsStr == "" {
+		codecsStr = defaultCodecs
+	}
+	specs, err := parseCodecSpecs(codecsStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid argument for %s: %v", codecsFlag, err)
+	}
+	if sweep, _ := cmd.Flags().GetBool(levelsFlag); sweep {
+		specs, err = expandLevelSweep(specs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument for %s: %v", codecsFlag, err)
+		}
+	}
+	return specs, nil
+}
+
+// returns the number of samples to generate, the glob of sample files, and the maximum dictionary size
+func getZstdDictFlags(cmd *cobra.Command) (numSamples int, dictFiles string, dictSize int, err error) {
+	numSamples, err = cmd.Flags().GetInt(zstdDictSamplesFlag)
+	if err != nil {
+		return 0, "", 0, err
+	}
+	if numSamples < 0 {
+		return 0, "", 0, fmt.Errorf("invalid argument for %s: must be 0 or greater", zstdDictSamplesFlag)
+	}
+	dictFiles, err = cmd.Flags().GetString(zstdDictFilesFlag)
+	if err != nil {
+		return 0, "", 0, err
+	}
+	dictSize, err = cmd.Flags().GetInt(zstdDictSizeFlag)
+	if err != nil {
+		return 0, "", 0, err
+	}
+	if dictSize <= 0 {
+		return 0, "", 0, fmt.Errorf("invalid argument for %s: must be 1 or greater", zstdDictSizeFlag)
+	}
+	return numSamples, dictFiles, dictSize, nil
+}
+
+// returns the per-message split mode, the fixed message size if splitting by size, and the number of JSON messages to generate
+func getMessagesFlags(cmd *cobra.Command) (mode string, size int, count int, err error) {
+	mode, err = cmd.Flags().GetString(messagesFlag)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	count, err = cmd.Flags().GetInt(messageCountFlag)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if count <= 0 {
+		return "", 0, 0, fmt.Errorf("invalid argument for %s: must be 1 or greater", messageCountFlag)
+	}
+	mode = strings.ToLower(mode)
+	if mode == "" || mode == splitLines || mode == splitJson {
+		return mode, 0, count, nil
+	}
+	size64, err := parseByteSize(mode, messagesFlag)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if size64 == 0 {
+		return "", 0, 0, fmt.Errorf("invalid argument for %s: message size must be 1 or greater", messagesFlag)
+	}
+	return mode, int(size64), count, nil
+}
+
+func getWarmupFlag(cmd *cobra.Command) (int, error) {
+	warmup, err := cmd.Flags().GetInt(warmupFlag)
+	if err != nil {
+		return 0, err
+	}
+	if warmup < 0 {
+		return 0, fmt.Errorf("value for %s must be 0 or greater", warmupFlag)
+	}
+	return warmup, nil
+}
+
+// chooses a random seed if the user did not set one. The seed is set on the flag so that it is
+// reported along with the other parameters, and every generator of the run uses it
+func setRandomSeed(cmd *cobra.Command) error {
+	if cmd.Flags().Changed(seedFlag) {
+		return nil
+	}
+	return cmd.Flags().Set(seedFlag, strconv.FormatInt(rand.Int63(), 10))
+}
+
+// returns the corpus options, or nil if the user did not ask to benchmark a directory or glob
+func getCorpusFlags(cmd *cobra.Command) (*CorpusOptions, error) {
+	dir, err := cmd.Flags().GetString(dirFlag)
+	if err != nil {
+		return nil, err
+	}
+	glob, err := cmd.Flags().GetString(globFlag)
+	if err != nil {
+		return nil, err
+	}
+	recursive, err := cmd.Flags().GetBool(recursiveFlag)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" && glob == "" {
+		if recursive {
+			return nil, fmt.Errorf("--%s requires --%s", recursiveFlag, dirFlag)
+		}
+		return nil, nil
+	}
+	if recursive && dir == "" {
+		return nil, fmt.Errorf("--%s requires --%s", recursiveFlag, dirFlag)
+	}
+	// these either need a single input, or combine results in a way which does not add up across files
+	for _, flag := range []string{messagesFlag, streamFlag, adaptiveFlag, concurrencyFlag, concurrencySweepFlag, printJsonFlag} {
+		if cmd.Flags().Changed(flag) {
+			return nil, fmt.Errorf("--%s cannot be used with a corpus of files", flag)
+		}
+	}
+	opts := &CorpusOptions{
+		Dir:       dir,
+		Glob:      glob,
+		Recursive: recursive,
+	}
+	limits := []struct {
+		flag  string
+		value *int64
+	}{
+		{minFileSizeFlag, &opts.MinFileSize},
+		{maxFileSizeFlag, &opts.MaxFileSize},
+		{maxCorpusSizeFlag, &opts.MaxTotalSize},
+	}
+	for _, limit := range limits {
+		sizeStr, _ := cmd.Flags().GetString(limit.flag)
+		size, err := parseByteSize(sizeStr, limit.flag)
+		if err != nil {
+			return nil, err
+		}
+		if size > math.MaxInt64 {
+			return nil, fmt.Errorf("invalid value '%s' for %s: too large", sizeStr, limit.flag)
+		}
+		*limit.value = int64(size)
+	}
+	if opts.MaxFileSize > 0 && opts.MinFileSize > opts.MaxFileSize {
+		return nil, fmt.Errorf("value for %s cannot be greater than %s", minFileSizeFlag, maxFileSizeFlag)
+	}
+	return opts, nil
+}
+
+// returns the streaming options, or nil if the user did not ask to stream the input
+func getStreamFlags(cmd *cobra.Command) (*StreamOptions, error) {
+	stream, err := cmd.Flags().GetBool(streamFlag)
+	if err != nil || !stream {
+		return nil, err
+	}
+	// these need the whole input in memory, or time many runs of the same input
+	for _, flag := range []string{messagesFlag, adaptiveFlag, concurrencyFlag, concurrencySweepFlag, printJsonFlag,
+		randJsonRecordsFlag, randJsonRecordsSizeFlag, targetSizeFlag} {
+		if cmd.Flags().Changed(flag) {
+			return nil, fmt.Errorf("--%s cannot be used with --%s", flag, streamFlag)
+		}
+	}
+	chunkStr, _ := cmd.Flags().GetString(streamChunkFlag)
+	chunkSize, err := parseByteSize(chunkStr, streamChunkFlag)
+	if err != nil {
+		return nil, err
+	}
+	if chunkSize == 0 || chunkSize > math.MaxInt32 {
+		return nil, fmt.Errorf("invalid value '%s' for %s: must be between 1B and 2GB", chunkStr, streamChunkFlag)
+	}
+	sizeStr, _ := cmd.Flags().GetString(streamSizeFlag)
+	size, err := parseByteSize(sizeStr, streamSizeFlag)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 || size > math.MaxInt {
+		return nil, fmt.Errorf("invalid value '%s' for %s: must be positive", sizeStr, streamSizeFlag)
+	}
+	return &StreamOptions{
+		ChunkSize: int(chunkSize),
+		Size:      int(size),
+	}, nil
+}
+
+// returns the concurrency options, or nil if the user did not ask for several workers
+func getConcurrencyFlags(cmd *cobra.Command) (*ConcurrencyOptions, error) {
+	workers, err := cmd.Flags().GetInt(concurrencyFlag)
+	if err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		return nil, fmt.Errorf("value for %s must be a positive integer", concurrencyFlag)
+	}
+	sweep, err := cmd.Flags().GetBool(concurrencySweepFlag)
+	if err != nil {
+		return nil, err
+	}
+	if workers == 1 && !sweep {
+		return nil, nil
+	}
+	// these are measured for the whole process, so they cannot be split between workers
+	for _, flag := range []string{messagesFlag, memoryFlag, cpuTimeFlag} {
+		if cmd.Flags().Changed(flag) {
+			return nil, fmt.Errorf("--%s cannot be used with several workers", flag)
+		}
+	}
+	distinct, err := cmd.Flags().GetBool(distinctInputsFlag)
+	if err != nil {
+		return nil, err
+	}
+	opts := &ConcurrencyOptions{
+		Workers:  []int{workers},
+		Distinct: distinct,
+	}
+	if sweep {
+		opts.Workers = getWorkerSweep()
+	}
+	return opts, nil
+}
+
+// returns the adaptive iteration options, or nil if the user did not ask for adaptive iterations
+func getAdaptiveFlags(cmd *cobra.Command) (*AdaptiveOptions, error) {
+	adaptive, err := cmd.Flags().GetBool(adaptiveFlag)
+	if err != nil || !adaptive {
+		return nil, err
+	}
+	budget, err := cmd.Flags().GetDuration(adaptiveTimeFlag)
+	if err != nil {
+		return nil, err
+	}
+	if budget <= 0 {
+		return nil, fmt.Errorf("value for %s must be positive", adaptiveTimeFlag)
+	}
+	maxRelErr, err := cmd.Flags().GetFloat64(adaptiveErrorFlag)
+	if err != nil {
+		return nil, err
+	}
+	if maxRelErr < 0 {
+		return nil, fmt.Errorf("value for %s must be 0 or greater", adaptiveErrorFlag)
+	}
+	return &AdaptiveOptions{
+		Budget:    budget,
+		MaxRelErr: maxRelErr,
+	}, nil
+}
+
+func getOutputFlags(cmd *cobra.Command) (*OutputOptions, error) {
+	format, err := cmd.Flags().GetString(outputFlag)
+	if err != nil {
+		return nil, err
+	}
+	format = strings.ToLower(format)
+	if !slices.Contains(outputFormats, format) {
+		return nil, fmt.Errorf("invalid argument for %s: must be one of: %s", outputFlag, strings.Join(outputFormats, ", "))
+	}
+	outFile, err := cmd.Flags().GetString(outFileFlag)
+	if err != nil {
+		return nil, err
+	}
+	saveFile, err := cmd.Flags().GetString(saveFlag)
+	if err != nil {
+		return nil, err
+	}
+	sortBy, err := cmd.Flags().GetString(sortFlag)
+	if err != nil {
+		return nil, err
+	}
+	sortBy = strings.ToLower(sortBy)
+	if sortBy != "" && !slices.Contains(sortKeys, sortBy) {
+		return nil, fmt.Errorf("invalid argument for %s: must be one of: %s", sortFlag, strings.Join(sortKeys, ", "))
+	}
+	return &OutputOptions{
+		Format:   format,
+		File:     outFile,
+		SaveFile: saveFile,
+		SortBy:   sortBy,
+	}, nil
+}
+
 func getSpeedFlag(cmd *cobra.Command) (uint64, error) {
 	speedStr, err := cmd.Flags().GetString(networkSpeedFlag)
 	if err != nil {
@@ -86,43 +465,45 @@ func getSpeedFlag(cmd *cobra.Command) (uint64, error) {
 	}
 }
 
-func parseSpeed(speedStr string) (out uint64, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			out, err = 0, fmt.Errorf("invalid value '%s' for %s: %v", speedStr, networkSpeedFlag, r)
-		}
-	}()
-	if speedStr == "" {
+func parseSpeed(speedStr string) (uint64, error) {
+	return parseByteSize(speedStr, networkSpeedFlag)
+}
+
+// byte size suffixes, with longer suffixes first so that e.g. KIB is not mistaken for B
+var byteUnits = []struct {
+	suffix string
+	mult   uint64
+}{
+	{"KIB", 1 << 10},
+	{"MIB", 1 << 20},
+	{"GIB", 1 << 30},
+	{"KB", 1000},
+	{"MB", 1000000},
+	{"GB", 1000000000},
+	{"B", 1},
+}
+
+// parses a number of bytes with an optional B, KB, MB, GB, KiB, MiB or GiB suffix, e.g. 128KB
+func parseByteSize(sizeStr, flag string) (uint64, error) {
+	if sizeStr == "" {
 		return 0, nil
 	}
-	upper := strings.ToUpper(speedStr)
+	upper := strings.ToUpper(sizeStr)
 	mult := uint64(1)
-	numEndIndex := len(upper)
-	if upper[len(upper)-1] == 'B' {
-		if len(upper) <= 2 {
-			return 0, fmt.Errorf("invalid value '%s' for %s", speedStr, networkSpeedFlag)
-		}
-		char := upper[len(upper)-2]
-		switch {
-		case char == 'K':
-			mult = 1000
-			numEndIndex--
-		case char == 'M':
-			mult = 1000000
-			numEndIndex--
-		case char == 'G':
-			mult = 1000000000
-			numEndIndex--
-		case char >= 48 && char <= 57:
-			mult = 1
-		default:
-			return 0, fmt.Errorf("invalid value '%s' for %s", speedStr, networkSpeedFlag)
+	valStr := upper
+	for _, unit := range byteUnits {
+		if trimmed, ok := strings.CutSuffix(upper, unit.suffix); ok {
+			valStr = trimmed
+			mult = unit.mult
+			break
 		}
 	}
-	valStr := upper[:numEndIndex-1]
 	val, err := strconv.ParseUint(valStr, 10, 64)
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("invalid value '%s' for %s", sizeStr, flag)
+	}
+	if val > math.MaxUint64/mult {
+		return 0, fmt.Errorf("invalid value '%s' for %s: too large", sizeStr, flag)
 	}
 	return val * mult, nil
 }
@@ -204,6 +585,148 @@ func parseRange(r string) (min, max int, err error) {
 	return min, max, nil
 }
 
+// returns the weights of the JSON value types to generate, or nil to generate fields and children of strings
+func getJsonTypesFlag(cmd *cobra.Command) ([]JsonValueWeight, error) {
+	typesStr, err := cmd.Flags().GetString(randJsonTypesFlag)
+	if err != nil || typesStr == "" {
+		return nil, err
+	}
+	var weights []JsonValueWeight
+	total := 0
+	for _, entry := range strings.Split(typesStr, ",") {
+		valueType, weightStr, hasWeight := strings.Cut(strings.TrimSpace(entry), "=")
+		valueType = strings.ToLower(valueType)
+		if !slices.Contains(jsonValueTypes, valueType) {
+			return nil, fmt.Errorf("invalid argument for %s: unknown type '%s', must be one of: %s",
+				randJsonTypesFlag, valueType, strings.Join(jsonValueTypes, ", "))
+		}
+		if slices.ContainsFunc(weights, func(w JsonValueWeight) bool { return w.Type == valueType }) {
+			return nil, fmt.Errorf("invalid argument for %s: type '%s' is given more than once", randJsonTypesFlag, valueType)
+		}
+		weight := 1
+		if hasWeight {
+			weight, err = strconv.Atoi(weightStr)
+			if err != nil || weight < 0 {
+				return nil, fmt.Errorf("invalid argument for %s: weight of '%s' must be 0 or greater", randJsonTypesFlag, valueType)
+			}
+		}
+		weights = append(weights, JsonValueWeight{Type: valueType, Weight: weight})
+		total += weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("invalid argument for %s: at least one weight must be positive", randJsonTypesFlag)
+	}
+	return weights, nil
+}
+
+// returns the min and max number of elements of generated arrays, or zeros to use the defaults
+func getArrayLenFlag(cmd *cobra.Command) (int, int, error) {
+	rangeStr, err := cmd.Flags().GetString(randJsonArrayLenRangeFlag)
+	if err != nil || rangeStr == "" {
+		return 0, 0, err
+	}
+	min, max, err := parseRange(rangeStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid argument for %s: %v", randJsonArrayLenRangeFlag, err)
+	}
+	if min < 0 {
+		return 0, 0, fmt.Errorf("invalid argument for %s: must be 0 or greater", randJsonArrayLenRangeFlag)
+	}
+	return min, max, nil
+}
+
+// returns the schema of the documents to generate, read from a schema file or inferred from an
+// example document, or nil to generate a tree of fields and children
+func getJsonSchemaFlag(cmd *cobra.Command) (*JsonSchema, error) {
+	schemaFile, err := cmd.Flags().GetString(randJsonSchemaFlag)
+	if err != nil {
+		return nil, err
+	}
+	if schemaFile != "" {
+		schema, err := readJsonSchema(schemaFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument for %s: %v", randJsonSchemaFlag, err)
+		}
+		return schema, nil
+	}
+	exampleFile, err := cmd.Flags().GetString(randJsonExampleFlag)
+	if err != nil || exampleFile == "" {
+		return nil, err
+	}
+	schema, err := readJsonExample(exampleFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid argument for %s: %v", randJsonExampleFlag, err)
+	}
+	return schema, nil
+}
+
+// returns the options of a batch of JSON records, or nil if the user did not ask for records
+func getRecordsFlags(cmd *cobra.Command) (*RecordOptions, error) {
+	count, err := cmd.Flags().GetInt(randJsonRecordsFlag)
+	if err != nil {
+		return nil, err
+	}
+	sizeStr, err := cmd.Flags().GetString(randJsonRecordsSizeFlag)
+	if err != nil {
+		return nil, err
+	}
+	format, err := cmd.Flags().GetString(randJsonRecordsFormatFlag)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 && sizeStr == "" {
+		if cmd.Flags().Changed(randJsonRecordsFormatFlag) {
+			return nil, fmt.Errorf("--%s requires --%s or --%s", randJsonRecordsFormatFlag, randJsonRecordsFlag, randJsonRecordsSizeFlag)
+		}
+		return nil, nil
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("invalid argument for %s: must be a positive integer", randJsonRecordsFlag)
+	}
+	if !slices.Contains(recordFormats, format) {
+		return nil, fmt.Errorf("invalid argument for %s: must be one of: %s", randJsonRecordsFormatFlag, strings.Join(recordFormats, ", "))
+	}
+	opts := &RecordOptions{
+		Count:  count,
+		Format: format,
+	}
+	if sizeStr != "" {
+		size, err := parseByteSize(sizeStr, randJsonRecordsSizeFlag)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 || size > math.MaxInt {
+			return nil, fmt.Errorf("invalid value '%s' for %s: must be positive", sizeStr, randJsonRecordsSizeFlag)
+		}
+		opts.Size = int(size)
+	}
+	return opts, nil
+}
+
+// returns the target size and the maximum size of generated JSON, zero meaning none
+func getJsonSizeFlags(cmd *cobra.Command) (int, int, error) {
+	targetStr, _ := cmd.Flags().GetString(targetSizeFlag)
+	target, err := parseByteSize(targetStr, targetSizeFlag)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxStr, _ := cmd.Flags().GetString(randJsonMaxSizeFlag)
+	maxSize, err := parseByteSize(maxStr, randJsonMaxSizeFlag)
+	if err != nil {
+		return 0, 0, err
+	}
+	if target > math.MaxInt || maxSize > math.MaxInt {
+		return 0, 0, fmt.Errorf("invalid value for %s or %s: too large", targetSizeFlag, randJsonMaxSizeFlag)
+	}
+	if targetStr != "" && target == 0 {
+		return 0, 0, fmt.Errorf("invalid value '%s' for %s: must be positive", targetStr, targetSizeFlag)
+	}
+	if maxSize > 0 && target > maxSize {
+		return 0, 0, fmt.Errorf("%s of %s is larger than %s of %s", targetSizeFlag, targetStr, randJsonMaxSizeFlag, maxStr)
+	}
+	return int(target), int(maxSize), nil
+}
+
 // returns a function to determine the number of key-value pairs for each element in the JSON tree
 func getNumFields(cmd *cobra.Command) (int, int, error) {
 	flagNum, err := cmd.Flags().GetInt(randJsonNumFieldsFlag)
@@ -241,18 +764,88 @@ func createBenchFlags(benchCmd *cobra.Command) {
 	benchCmd.Flags().String(randJsonDictFile, "", "File containing a dictionary of words to use in JSON string values")
 	benchCmd.Flags().Int(randJsonDictSize, 0, "Number of words to randomly generate as a dictionary for JSON string values")
 	benchCmd.MarkFlagsMutuallyExclusive(randJsonDictFile, randJsonDictSize)
+	// typed values
+	benchCmd.Flags().String(randJsonTypesFlag, "", fmt.Sprintf("Comma separated mix of JSON value types to generate as type[=weight], e.g. string=3,int,bool, which also nests children as objects (types: %s)", strings.Join(jsonValueTypes, ", ")))
+	benchCmd.Flags().String(randJsonArrayLenRangeFlag, "", "Min and max number of elements of generated arrays, with --json-types")
+	// schema driven documents
+	benchCmd.Flags().String(randJsonSchemaFlag, "", "JSON Schema file which generated documents conform to, instead of a tree of fields and children")
+	benchCmd.Flags().String(randJsonExampleFlag, "", "Example JSON document to generate documents of the same shape as, with randomized values")
+	benchCmd.MarkFlagsMutuallyExclusive(randJsonSchemaFlag, randJsonExampleFlag)
+	// batches of records
+	benchCmd.Flags().Int(randJsonRecordsFlag, 0, "Generate this many JSON documents as a batch of records, which share the same keys with different values")
+	benchCmd.Flags().String(randJsonRecordsSizeFlag, "", "Generate JSON records up to this total size, e.g. 10MB, instead of a number of records")
+	benchCmd.Flags().String(randJsonRecordsFormatFlag, recordsNdjson, fmt.Sprintf("Format of a batch of JSON records (formats: %s)", strings.Join(recordFormats, ", ")))
+	benchCmd.MarkFlagsMutuallyExclusive(randJsonRecordsFlag, randJsonRecordsSizeFlag)
+	// size of generated input
+	benchCmd.Flags().String(targetSizeFlag, "", "Approximate size of the generated JSON, e.g. 10MB, reached by giving the root of the tree as many children as needed")
+	benchCmd.Flags().String(randJsonMaxSizeFlag, defaultJsonMaxSize, "Generating JSON larger than this size fails, e.g. 10MB, or 0 for no limit")
+	benchCmd.Flags().Int64(seedFlag, 0, "Seed of the random JSON generator, to generate the same input as an earlier run. By default a random seed is chosen and displayed")
 	// debug
 	benchCmd.Flags().Bool(printJsonFlag, false, "If BenComp should print the JSON it used in benchmarking")
 
 	// file input
-	benchCmd.Flags().StringP(fileInputFlag, fileInputFlagShort, "", "Specify a file to be used for compression benchmarking")
-	benchCmd.MarkFlagsOneRequired(isRandInput, fileInputFlag)
-	benchCmd.MarkFlagsMutuallyExclusive(isRandInput, fileInputFlag)
+	benchCmd.Flags().StringP(fileInputFlag, fileInputFlagShort, "", "Specify a file to be used for compression benchmarking, or - to read from stdin")
+
+	// corpus input
+	benchCmd.Flags().String(dirFlag, "", "Benchmark every file in this directory, reporting results per file extension and for the whole directory")
+	benchCmd.Flags().String(globFlag, "", "Benchmark every file matching this glob, e.g. 'samples/*.json', reporting results per file extension and for all files")
+	benchCmd.Flags().Bool(recursiveFlag, false, "Also benchmark the files in subdirectories of --dir")
+	benchCmd.Flags().String(minFileSizeFlag, "", "Skip corpus files smaller than this size, e.g. 1KB")
+	benchCmd.Flags().String(maxFileSizeFlag, "", "Skip corpus files larger than this size, e.g. 10MB")
+	benchCmd.Flags().String(maxCorpusSizeFlag, "", "Skip corpus files once their total size would exceed this size, e.g. 1GB")
+	benchCmd.Flags().Bool(showFilesFlag, false, "Display the results of every corpus file, not just those of every extension")
+	benchCmd.MarkFlagsOneRequired(isRandInput, fileInputFlag, dirFlag, globFlag)
+	benchCmd.MarkFlagsMutuallyExclusive(isRandInput, fileInputFlag, dirFlag, globFlag)
+
+	// codec selection
+	benchCmd.Flags().String(codecsFlag, "", fmt.Sprintf("Comma separated list of codecs to benchmark as name[:level], e.g. gzip,zstd,zlib:9 (available: %s)", strings.Join(RegisteredCodecNames(), ", ")))
+	benchCmd.Flags().Bool(levelsFlag, false, "Benchmark every supported compression level of each selected codec")
+
+	// zstd dictionary
+	benchCmd.Flags().Int(zstdDictSamplesFlag, 0, "Number of random JSON samples to train a zstd dictionary with, then benchmark zstd with and without it")
+	benchCmd.Flags().String(zstdDictFilesFlag, "", "Glob of sample files to train a zstd dictionary with, then benchmark zstd with and without it")
+	benchCmd.MarkFlagsMutuallyExclusive(zstdDictSamplesFlag, zstdDictFilesFlag)
+	benchCmd.Flags().Int(zstdDictSizeFlag, defaultZstdDictSize, "Maximum size in bytes of the trained zstd dictionary")
+
+	// round trip verification
+
+	// encoder reuse
+	benchCmd.Flags().Bool(reuseFlag, false, "Create each encoder and decoder once and reset it for every payload, reporting the setup time separately")
+
+	// streaming
+	benchCmd.Flags().Bool(streamFlag, false, "Stream the input through each codec in chunks instead of holding it in memory")
+	benchCmd.Flags().String(streamChunkFlag, "64KiB", "Size of each write to the encoder and each read from the decoder when streaming")
+	benchCmd.Flags().String(streamSizeFlag, "100MB", "Amount of random JSON to generate when streaming with --rand-gen")
+
+	// concurrency
+	benchCmd.Flags().Int(concurrencyFlag, 1, "Number of workers compressing and decompressing at the same time, each on the whole input")
+	benchCmd.Flags().Bool(concurrencySweepFlag, false, "Benchmark each codec with every number of workers from 1 to GOMAXPROCS, showing how it scales")
+	benchCmd.MarkFlagsMutuallyExclusive(concurrencyFlag, concurrencySweepFlag)
+	benchCmd.Flags().Bool(distinctInputsFlag, false, "Give each worker a different input instead of sharing one: its own random JSON, or the input starting at a different offset")
+
+	// per-message benchmarking
+	benchCmd.Flags().String(messagesFlag, "", "Compress each message independently, splitting the input by 'lines', by a fixed size e.g. 4KB, or generating 'json' documents")
+	benchCmd.Flags().Int(messageCountFlag, defaultMessageCount, "Number of JSON documents to generate when using --messages json")
+
+	// output
+	benchCmd.Flags().StringP(outputFlag, outputFlagShort, outputTable, fmt.Sprintf("Format of the results, one of: %s", strings.Join(outputFormats, ", ")))
+	benchCmd.Flags().String(outFileFlag, "", "Write the results to this file instead of stdout")
+	benchCmd.Flags().String(saveFlag, "", "Also save the results as a JSON report to this file, to be used as a baseline by the compare command")
+	benchCmd.Flags().String(sortFlag, "", fmt.Sprintf("Sort the results best first by one of: %s", strings.Join(sortKeys, ", ")))
 
 	// optional output
 	benchCmd.Flags().String(networkSpeedFlag, "", "Number of bytes (not bits) per second on the wire, e.g. 128KB")
 	benchCmd.Flags().Int(networkPayloadsFlag, 0, "Number of payloads used in system performance estimate")
 	benchCmd.Flags().Bool(printCTimeFlag, false, "If set, will display time spent compressing in a separate column")
 	benchCmd.Flags().Bool(printDTimeFlag, false, "If set, will display time spent decompressing in a separate column")
+	benchCmd.Flags().Bool(throughputFlag, false, "If set, will display compression and decompression speed in MB/s of uncompressed data")
+	benchCmd.Flags().Bool(cpuTimeFlag, false, "If set, will display the CPU time of compression and decompression, and the average number of cores they kept busy")
+	benchCmd.Flags().Bool(memoryFlag, false, "If set, will display bytes allocated, allocation count and peak heap growth of compression and decompression")
 	benchCmd.Flags().IntP(countFlag, countFlagShort, 1, "Repeat the benchmark multiple times and record the median values")
+	benchCmd.Flags().Bool(adaptiveFlag, false, "Repeat each codec until its timings are stable or its time budget is spent, instead of a fixed --count")
+	benchCmd.Flags().Duration(adaptiveTimeFlag, defaultAdaptiveBudget, "Maximum time to spend repeating each codec with --adaptive")
+	benchCmd.Flags().Float64(adaptiveErrorFlag, defaultAdaptiveRelErr, "Stop repeating a codec with --adaptive once its 95% confidence interval is within this fraction of the mean")
+	benchCmd.MarkFlagsMutuallyExclusive(countFlag, adaptiveFlag)
+	benchCmd.Flags().Int(warmupFlag, 0, "Number of extra benchmark runs to perform and discard before the counted runs")
+	benchCmd.Flags().String(statsFlag, "", fmt.Sprintf("Comma separated list of statistics over the total time of all runs to display, from: all, %s", strings.Join(statNames, ", ")))
 }
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"runtime"
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// how often the heap size is sampled while a codec is running
+	memorySampleInterval = 100 * time.Microsecond
+
+	heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+)
+
+// MemoryStats describes the memory used by a single compression or decompression
+type MemoryStats struct {
+	AllocBytes int `json:"alloc_bytes"`
+	Allocs     int `json:"allocs"`
+	// largest growth of the heap, including garbage which was not yet collected, over the heap
+	// size just before the codec started
+	PeakHeap int `json:"peak_heap_bytes"`
+}
+
+// memoryMeter measures the memory used between its start and stop, leaving out the time it is paused
+type memoryMeter struct {
+	before   runtime.MemStats
+	baseline uint64
+	peak     atomic.Uint64
+	ticker   *time.Ticker
+	stop     chan struct{}
+	done     chan struct{}
+	// set once the meter is stopped
+	stats *MemoryStats
+
+	paused atomic.Bool
+	// counters and heap size when the meter was last paused
+	pausedAt   runtime.MemStats
+	pausedHeap uint64
+	// allocations and heap growth while the meter was paused, which are not counted
+	excludedBytes  uint64
+	excludedAllocs uint64
+	excludedHeap   atomic.Uint64
+}
+
+// collects garbage so that the heap only holds live objects, then starts sampling its size
+func startMemoryMeter() *memoryMeter {
+	runtime.GC()
+	m := &memoryMeter{
+		ticker: time.NewTicker(memorySampleInterval),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	m.baseline = readHeapObjects(newHeapSample())
+	m.peak.Store(m.baseline)
+	// the sampler is started before the allocations are counted so that it is not counted itself
+	go m.sample(newHeapSample())
+	runtime.ReadMemStats(&m.before)
+	return m
+}
+
+func (m *memoryMeter) sample(sample []metrics.Sample) {
+	defer close(m.done)
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-m.ticker.C:
+			if !m.paused.Load() {
+				m.observeCounted(readHeapObjects(sample))
+			}
+		}
+	}
+}
+
+// observes the heap size without the growth while the meter was paused
+func (m *memoryMeter) observeCounted(heap uint64) {
+	m.observe(heap - min(heap, m.excludedHeap.Load()))
+}
+
+func (m *memoryMeter) observe(heap uint64) {
+	for {
+		peak := m.peak.Load()
+		if heap <= peak || m.peak.CompareAndSwap(peak, heap) {
+			return
+		}
+	}
+}
+
+// stops counting until the meter is resumed, so that work which is not part of the codec, such as
+// generating its input, is left out. Pausing a nil or paused meter does nothing
+func (m *memoryMeter) Pause() {
+	if m == nil || m.stats != nil || m.paused.Load() {
+		return
+	}
+	// the counters are read first so that the allocations of pausing are not counted
+	runtime.ReadMemStats(&m.pausedAt)
+	m.paused.Store(true)
+	m.pausedHeap = readHeapObjects(newHeapSample())
+}
+
+// counts again after the meter was paused. The heap growth while it was paused is taken off every
+// later sample, even once a garbage collection frees it, so the peak may be slightly too low
+func (m *memoryMeter) Resume() {
+	if m == nil || m.stats != nil || !m.paused.Load() {
+		return
+	}
+	if heap := readHeapObjects(newHeapSample()); heap > m.pausedHeap {
+		m.excludedHeap.Add(heap - m.pausedHeap)
+	}
+	// the counters are read last so that the allocations of resuming are not counted
+	var now runtime.MemStats
+	runtime.ReadMemStats(&now)
+	m.excludedBytes += now.TotalAlloc - m.pausedAt.TotalAlloc
+	m.excludedAllocs += now.Mallocs - m.pausedAt.Mallocs
+	m.paused.Store(false)
+}
+
+// stops sampling and returns the memory used since the meter was started. Stopping the meter again
+// returns the same stats and stopping a nil meter returns nil, so that every return path can stop it
+func (m *memoryMeter) Stop() *MemoryStats {
+	if m == nil {
+		return nil
+	}
+	if m.stats != nil {
+		return m.stats
+	}
+	// nothing since the meter was paused is counted
+	m.Resume()
+	var after runtime.MemStats
+	m.observeCounted(readHeapObjects(newHeapSample()))
+	runtime.ReadMemStats(&after)
+	m.ticker.Stop()
+	close(m.stop)
+	<-m.done
+	m.stats = &MemoryStats{
+		AllocBytes: int(after.TotalAlloc - m.before.TotalAlloc - m.excludedBytes),
+		Allocs:     int(after.Mallocs - m.before.Mallocs - m.excludedAllocs),
+		PeakHeap:   int(m.peak.Load() - m.baseline),
+	}
+	return m.stats
+}
+
+func newHeapSample() []metrics.Sample {
+	return []metrics.Sample{{Name: heapObjectsMetric}}
+}
+
+// returns the bytes of heap memory occupied by objects, live or not yet collected
+func readHeapObjects(sample []metrics.Sample) uint64 {
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+// adds the memory used by b to a, taking the larger of the peaks since the two did not run at the same time
+func addMemoryStats(a, b *MemoryStats) *MemoryStats {
+	if a == nil || b == nil {
+		return b
+	}
+	return &MemoryStats{
+		AllocBytes: a.AllocBytes + b.AllocBytes,
+		Allocs:     a.Allocs + b.Allocs,
+		PeakHeap:   max(a.PeakHeap, b.PeakHeap),
+	}
+}
+
+// combines the memory stats of repeated runs of the same codec by taking the median of each value
+func aggregateMemoryStats(stats []*MemoryStats) *MemoryStats {
+	if len(stats) == 0 || stats[0] == nil {
+		return nil
+	}
+	n := len(stats)
+	allocBytes := make([]int, n)
+	allocs := make([]int, n)
+	peaks := make([]int, n)
+	for i, stat := range stats {
+		allocBytes[i] = stat.AllocBytes
+		allocs[i] = stat.Allocs
+		peaks[i] = stat.PeakHeap
+	}
+	return &MemoryStats{
+		AllocBytes: findMedianInt(allocBytes),
+		Allocs:     findMedianInt(allocs),
+		PeakHeap:   findMedianInt(peaks),
+	}
+}
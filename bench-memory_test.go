@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var memorySink []byte
+
+func TestMemoryMeter(t *testing.T) {
+	size := 4 << 20
+	meter := startMemoryMeter()
+	memorySink = make([]byte, size)
+	stats := meter.Stop()
+	memorySink = nil
+	if stats.AllocBytes < size {
+		t.Errorf("expected at least %d bytes allocated but got %d", size, stats.AllocBytes)
+	}
+	if stats.Allocs < 1 {
+		t.Errorf("expected at least 1 allocation but got %d", stats.Allocs)
+	}
+	if stats.PeakHeap < size {
+		t.Errorf("expected a peak heap growth of at least %d bytes but got %d", size, stats.PeakHeap)
+	}
+	// every return path stops the meter, so stopping it again must be safe
+	if again := meter.Stop(); again != stats {
+		t.Errorf("expected stopping the meter again to return the same stats but got %+v", again)
+	}
+	var noMeter *memoryMeter
+	if noStats := noMeter.Stop(); noStats != nil {
+		t.Errorf("expected no stats from a nil meter but got %+v", noStats)
+	}
+}
+
+func TestMemoryMeterPause(t *testing.T) {
+	size := 4 << 20
+	meter := startMemoryMeter()
+	meter.Pause()
+	// allocations while paused are not counted, nor is the heap they grow
+	memorySink = make([]byte, 4*size)
+	meter.Resume()
+	memorySink = make([]byte, size)
+	stats := meter.Stop()
+	memorySink = nil
+	if stats.AllocBytes < size || stats.AllocBytes >= 2*size {
+		t.Errorf("expected between %d and %d bytes allocated but got %d", size, 2*size, stats.AllocBytes)
+	}
+	// the heap growth while paused is taken off, which may take off a little too much
+	if stats.PeakHeap < size/2 || stats.PeakHeap >= 2*size {
+		t.Errorf("expected a peak heap growth between %d and %d bytes but got %d", size/2, 2*size, stats.PeakHeap)
+	}
+	var noMeter *memoryMeter
+	noMeter.Pause()
+	noMeter.Resume()
+}
+
+func TestRunRoundTripMemory(t *testing.T) {
+	alloc := func(b []byte) ([]byte, time.Duration, error) {
+		memorySink = make([]byte, 1<<20)
+		return b, 0, nil
+	}
+	opts := NewRunOptions()
+	res, err := runRoundTrip("test", []byte("test"), alloc, alloc, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.CompressMemory != nil || res.DecompressMemory != nil {
+		t.Errorf("expected no memory stats when not measuring memory")
+	}
+	opts.MeasureMemory = true
+	res, err = runRoundTrip("test", []byte("test"), alloc, alloc, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.CompressMemory == nil || res.CompressMemory.AllocBytes < 1<<20 {
+		t.Errorf("expected compression to allocate at least 1MB but got %+v", res.CompressMemory)
+	}
+	if res.DecompressMemory == nil || res.DecompressMemory.AllocBytes < 1<<20 {
+		t.Errorf("expected decompression to allocate at least 1MB but got %+v", res.DecompressMemory)
+	}
+}
+
+func TestAggregateMemoryStats(t *testing.T) {
+	if out := aggregateMemoryStats([]*MemoryStats{nil, nil}); out != nil {
+		t.Errorf("expected nil but got %+v", out)
+	}
+	stats := []*MemoryStats{
+		{AllocBytes: 100, Allocs: 3, PeakHeap: 50},
+		{AllocBytes: 300, Allocs: 1, PeakHeap: 10},
+		{AllocBytes: 200, Allocs: 2, PeakHeap: 30},
+	}
+	exp := &MemoryStats{AllocBytes: 200, Allocs: 2, PeakHeap: 30}
+	if out := aggregateMemoryStats(stats); !reflect.DeepEqual(out, exp) {
+		t.Errorf("expected %+v but got %+v", exp, out)
+	}
+	exp = &MemoryStats{AllocBytes: 400, Allocs: 4, PeakHeap: 50}
+	if out := addMemoryStats(stats[0], stats[1]); !reflect.DeepEqual(out, exp) {
+		t.Errorf("expected %+v but got %+v", exp, out)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	// values for the messages flag, any other value is a fixed message size
+	splitLines = "lines"
+	splitJson  = "json"
+
+	defaultMessageCount = 100
+)
+
+// MessageStats summarizes a benchmark in which each message was compressed independently
+type MessageStats struct {
+	Count      int           `json:"count"`
+	LatencyP50 time.Duration `json:"latency_p50_ns"`
+	LatencyP90 time.Duration `json:"latency_p90_ns"`
+	LatencyP99 time.Duration `json:"latency_p99_ns"`
+	Overhead   int           `json:"overhead"`
+}
+
+// gets the messages to benchmark according to user flags, or nil if the user did not ask for per-message benchmarking.
+// Also returns the input the messages were taken from
+func getBenchmarkMessages(src *inputSource) ([]byte, [][]byte, error) {
+	mode, size, count, err := getMessagesFlags(src.cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mode == splitJson {
+		isRand, _ := src.cmd.Flags().GetBool(isRandInput)
+		if !isRand {
+			return nil, nil, fmt.Errorf("--%s %s requires --%s", messagesFlag, splitJson, isRandInput)
+		}
+		messages := make([][]byte, 0, count)
+		for range count {
+			message, err := getRandInput(src)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error while generating random input: %v", err)
+			}
+			messages = append(messages, message)
+		}
+		return bytes.Join(messages, []byte("\n")), messages, nil
+	}
+	input, err := getBenchmarkInput(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch mode {
+	case "":
+		return input, nil, nil
+	case splitLines:
+		return input, splitMessageLines(input), nil
+	default:
+		return input, splitMessageSize(input, size), nil
+	}
+}
+
+// splits input on newlines, dropping empty lines
+func splitMessageLines(input []byte) [][]byte {
+	messages := [][]byte{}
+	for _, line := range bytes.Split(input, []byte("\n")) {
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if len(line) > 0 {
+			messages = append(messages, line)
+		}
+	}
+	return messages
+}
+
+// splits input into messages of the given size, the last message may be shorter
+func splitMessageSize(input []byte, size int) [][]byte {
+	messages := make([][]byte, 0, len(input)/size+1)
+	for len(input) > size {
+		messages = append(messages, input[:size])
+		input = input[size:]
+	}
+	if len(input) > 0 {
+		messages = append(messages, input)
+	}
+	return messages
+}
+
+// compresses and decompresses each message independently, then combines the results.
+// Times and sizes are totals across all messages, and the ratio is the ratio of the totals
+func runMessageBenchmark(benchmarker Benchmarker, messages [][]byte, opts *RunOptions) (*BenchmarkResult, error) {
+	// some codecs write nothing at all for an empty input, so the framing overhead is
+	// estimated from a single byte message instead
+	overheadResult, err := benchmarker.RunBenchmark([]byte{0}, opts)
+	if err != nil {
+		return nil, err
+	}
+	total := &BenchmarkResult{
+		Name:      overheadResult.Name,
+		SetupTime: overheadResult.SetupTime,
+	}
+	latencies := make([]time.Duration, 0, len(messages))
+	inputSize := 0
+	for _, message := range messages {
+		result, err := benchmarker.RunBenchmark(message, opts)
+		if err != nil {
+			return nil, err
+		}
+		total.CompressTime += result.CompressTime
+		total.DecompressTime += result.DecompressTime
+		total.CompressCPUTime += result.CompressCPUTime
+		total.DecompressCPUTime += result.DecompressCPUTime
+		total.CompressedSize += result.CompressedSize
+		total.CompressMemory = addMemoryStats(total.CompressMemory, result.CompressMemory)
+		total.DecompressMemory = addMemoryStats(total.DecompressMemory, result.DecompressMemory)
+		inputSize += len(message)
+		latencies = append(latencies, result.GetTotalTime())
+	}
+	total.InputSize = inputSize
+	if inputSize > 0 {
+		total.Ratio = float64(total.CompressedSize) / float64(inputSize)
+	}
+	total.setThroughput()
+	slices.Sort(latencies)
+	total.Messages = &MessageStats{
+		Count:      len(messages),
+		LatencyP50: percentileTime(latencies, 50),
+		LatencyP90: percentileTime(latencies, 90),
+		LatencyP99: percentileTime(latencies, 99),
+		Overhead:   max(0, overheadResult.CompressedSize-1),
+	}
+	return total, nil
+}
+
+// combines the message stats of repeated runs of the same codec by taking the median of each value
+func aggregateMessageStats(stats []*MessageStats) *MessageStats {
+	if len(stats) == 0 || stats[0] == nil {
+		return nil
+	}
+	n := len(stats)
+	p50s := make([]time.Duration, n)
+	p90s := make([]time.Duration, n)
+	p99s := make([]time.Duration, n)
+	for i, stat := range stats {
+		p50s[i] = stat.LatencyP50
+		p90s[i] = stat.LatencyP90
+		p99s[i] = stat.LatencyP99
+	}
+	return &MessageStats{
+		Count:      stats[0].Count,
+		LatencyP50: findMedianTime(p50s),
+		LatencyP90: findMedianTime(p90s),
+		LatencyP99: findMedianTime(p99s),
+		Overhead:   stats[0].Overhead,
+	}
+}
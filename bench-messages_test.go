@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitMessages(t *testing.T) {
+	type testData struct {
+		name  string
+		input string
+		size  int
+		exp   []string
+	}
+	tests := []testData{
+		{
+			name:  "lines",
+			input: "{\"a\":1}\n{\"b\":2}\r\n\n{\"c\":3}",
+			exp:   []string{"{\"a\":1}", "{\"b\":2}", "{\"c\":3}"},
+		},
+		{
+			name:  "size exact",
+			input: "aabbcc",
+			size:  2,
+			exp:   []string{"aa", "bb", "cc"},
+		},
+		{
+			name:  "size remainder",
+			input: "aabbc",
+			size:  2,
+			exp:   []string{"aa", "bb", "c"},
+		},
+		{
+			name:  "size larger than input",
+			input: "abc",
+			size:  10,
+			exp:   []string{"abc"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var messages [][]byte
+			if test.size == 0 {
+				messages = splitMessageLines([]byte(test.input))
+			} else {
+				messages = splitMessageSize([]byte(test.input), test.size)
+			}
+			out := make([]string, 0, len(messages))
+			for _, message := range messages {
+				out = append(out, string(message))
+			}
+			if !reflect.DeepEqual(out, test.exp) {
+				t.Errorf("expected %q but got %q", test.exp, out)
+			}
+		})
+	}
+}
+
+func TestRunMessageBenchmark(t *testing.T) {
+	messages := [][]byte{
+		[]byte("the quick brown fox jumps over the lazy dog"),
+		[]byte("the quick brown fox jumps over the lazy cat"),
+	}
+	result, err := runMessageBenchmark(NewDeflater(stdGzip, -1), messages, NewRunOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Name != "std-gzip-6" {
+		t.Errorf("expected name std-gzip-6 but got %s", result.Name)
+	}
+	if result.Messages == nil || result.Messages.Count != 2 {
+		t.Fatalf("expected stats for 2 messages but got %v", result.Messages)
+	}
+	// a gzip member has a 10 byte header and an 8 byte trailer
+	if result.Messages.Overhead < 18 {
+		t.Errorf("expected at least 18 bytes of overhead but got %d", result.Messages.Overhead)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	outputTable    = "table"
+	outputJson     = "json"
+	outputCsv      = "csv"
+	outputMarkdown = "markdown"
+
+	// incremented whenever the JSON report changes in a way that breaks readers
+	reportVersion = 1
+)
+
+var (
+	outputFormats = []string{outputTable, outputJson, outputCsv, outputMarkdown}
+
+	csvHeader = []string{
+		"name", "input_size", "compress_time_ns", "decompress_time_ns", "total_time_ns",
+		"compress_cpu_time_ns", "decompress_cpu_time_ns", "setup_time_ns",
+		"compressed_size", "ratio", "compress_mb_per_sec", "decompress_mb_per_sec", "iterations",
+		"compress_alloc_bytes", "compress_allocs", "compress_peak_heap_bytes",
+		"decompress_alloc_bytes", "decompress_allocs", "decompress_peak_heap_bytes", "total_min_ns", "total_max_ns", "total_mean_ns",
+		"total_stddev_ns", "total_p50_ns", "total_p90_ns", "total_p99_ns", "total_ci95_ns",
+		"workers", "wall_time_ns", "aggregate_mb_per_sec", "cpu_time_ns", "scaling",
+		"messages", "latency_p50_ns", "latency_p90_ns", "latency_p99_ns", "overhead",
+		"corpus_file", "corpus_extension",
+	}
+)
+
+type OutputOptions struct {
+	Format string
+	File   string
+	// file to save a JSON report to in addition to the requested output
+	SaveFile string
+	// metric to sort the results by, or empty to keep the order of the codecs
+	SortBy string
+}
+
+// Report is the machine readable form of a benchmark run
+type Report struct {
+	Version   int         `json:"version"`
+	Timestamp time.Time   `json:"timestamp"`
+	GoVersion string      `json:"go_version"`
+	OS        string      `json:"os"`
+	Arch      string      `json:"arch"`
+	NumCPU    int         `json:"num_cpu"`
+	Input     ReportInput `json:"input"`
+	// every flag the user set, by flag name
+	Parameters map[string]string `json:"parameters"`
+	ZstdDict   *ZstdDict         `json:"zstd_dict,omitempty"`
+	// the results of the whole corpus when benchmarking a corpus of files
+	Results []*BenchmarkResult `json:"results"`
+	Corpus  *CorpusReport      `json:"corpus,omitempty"`
+}
+
+// ReportInput describes the data which was compressed
+type ReportInput struct {
+	Source   string `json:"source"`
+	Size     int    `json:"size"`
+	Messages int    `json:"messages,omitempty"`
+	// only set when streaming the input
+	ChunkSize int `json:"chunk_size,omitempty"`
+	// only set for random JSON, the seed which generates the same input again
+	Seed *int64 `json:"seed,omitempty"`
+}
+
+func newReport(cmd *cobra.Command, inputSize int, results []*BenchmarkResult, zdict *ZstdDict) *Report {
+	params := map[string]string{}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		params[f.Name] = f.Value.String()
+	})
+	source := "random JSON"
+	if filename, _ := cmd.Flags().GetString(fileInputFlag); filename == stdinFile {
+		source = "stdin"
+	} else if filename != "" {
+		source = filename
+	} else if corpusSource := getCorpusSource(cmd); corpusSource != "" {
+		source = corpusSource
+	}
+	messages := 0
+	if len(results) > 0 && results[0].Messages != nil {
+		messages = results[0].Messages.Count
+	}
+	var seed *int64
+	if isRand, _ := cmd.Flags().GetBool(isRandInput); isRand {
+		value, _ := cmd.Flags().GetInt64(seedFlag)
+		seed = &value
+	}
+	return &Report{
+		Version:   reportVersion,
+		Timestamp: time.Now().UTC(),
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		NumCPU:    runtime.NumCPU(),
+		Input: ReportInput{
+			Source:   source,
+			Size:     inputSize,
+			Messages: messages,
+			Seed:     seed,
+		},
+		Parameters: params,
+		ZstdDict:   zdict,
+		Results:    results,
+	}
+}
+
+// writes the report in the requested format, to the requested file or stdout, and saves it if requested
+func writeReport(output *OutputOptions, report *Report, input []byte, opts *PrintOptions) error {
+	if output.SaveFile != "" {
+		if err := saveReport(output.SaveFile, report); err != nil {
+			return err
+		}
+	}
+	w := io.Writer(os.Stdout)
+	if output.File != "" {
+		file, err := os.Create(output.File)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	switch output.Format {
+	case outputJson:
+		return writeJsonReport(w, report)
+	case outputCsv:
+		return writeCsvReport(w, report)
+	case outputMarkdown:
+		printMarkdownResults(w, report, input, opts)
+	default:
+		printResults(w, report, input, opts)
+	}
+	if report.ZstdDict != nil {
+		printZstdDictSummary(w, report.ZstdDict, report.Results)
+	}
+	return nil
+}
+
+func saveReport(path string, report *Report) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return writeJsonReport(file, report)
+}
+
+func writeJsonReport(w io.Writer, report *Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// writes one row of raw numbers per result, columns which do not apply to the run are left empty.
+// The results of a whole corpus are followed by those of every extension and file
+func writeCsvReport(w io.Writer, report *Report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, result := range report.Results {
+		if err := cw.Write(append(getCsvRow(result), "", "")); err != nil {
+			return err
+		}
+	}
+	if corpus := report.Corpus; corpus != nil {
+		for _, entry := range corpus.Extensions {
+			for _, result := range entry.Results {
+				if err := cw.Write(append(getCsvRow(result), "", entry.Name)); err != nil {
+					return err
+				}
+			}
+		}
+		for _, entry := range corpus.Files {
+			for _, result := range entry.Results {
+				if err := cw.Write(append(getCsvRow(result), entry.Name, corpusExtension(entry.Name))); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// returns the csv columns of a result, apart from the corpus columns
+func getCsvRow(result *BenchmarkResult) []string {
+	row := []string{
+		result.Name,
+		strconv.Itoa(result.InputSize),
+		formatNs(result.CompressTime),
+		formatNs(result.DecompressTime),
+		formatNs(result.GetTotalTime()),
+		formatNs(result.CompressCPUTime),
+		formatNs(result.DecompressCPUTime),
+		formatNs(result.SetupTime),
+		strconv.Itoa(result.CompressedSize),
+		strconv.FormatFloat(result.Ratio, 'f', -1, 64),
+		strconv.FormatFloat(result.CompressSpeed, 'f', -1, 64),
+		strconv.FormatFloat(result.DecompressSpeed, 'f', -1, 64),
+		strconv.Itoa(result.Iterations),
+	}
+	row = append(row, formatMemoryStats(result.CompressMemory)...)
+	row = append(row, formatMemoryStats(result.DecompressMemory)...)
+	if stats := result.TotalStats; stats != nil {
+		row = append(row,
+			formatNs(stats.Min), formatNs(stats.Max), formatNs(stats.Mean), formatNs(stats.StdDev),
+			formatNs(stats.P50), formatNs(stats.P90), formatNs(stats.P99), formatNs(stats.CI95),
+		)
+	} else {
+		row = append(row, "", "", "", "", "", "", "", "")
+	}
+	if concurrency := result.Concurrency; concurrency != nil {
+		row = append(row,
+			strconv.Itoa(concurrency.Workers), formatNs(concurrency.WallTime),
+			strconv.FormatFloat(concurrency.Throughput, 'f', -1, 64), formatNs(concurrency.CPUTime),
+			strconv.FormatFloat(concurrency.Scaling, 'f', -1, 64),
+		)
+	} else {
+		row = append(row, "", "", "", "", "")
+	}
+	if messages := result.Messages; messages != nil {
+		row = append(row,
+			strconv.Itoa(messages.Count), formatNs(messages.LatencyP50), formatNs(messages.LatencyP90),
+			formatNs(messages.LatencyP99), strconv.Itoa(messages.Overhead),
+		)
+	} else {
+		row = append(row, "", "", "", "", "")
+	}
+	return row
+}
+
+// returns the csv columns of the memory stats, which are empty if memory was not measured
+func formatMemoryStats(stats *MemoryStats) []string {
+	if stats == nil {
+		return []string{"", "", ""}
+	}
+	return []string{strconv.Itoa(stats.AllocBytes), strconv.Itoa(stats.Allocs), strconv.Itoa(stats.PeakHeap)}
+}
+
+// prints the same columns as the result table, as a markdown table
+func printMarkdownResults(w io.Writer, report *Report, input []byte, opts *PrintOptions) {
+	printInputSummary(w, report, input, opts)
+	fields, printers := getResultColumns(opts)
+	if report.Corpus != nil {
+		printCorpusTables(w, report.Corpus, fields, printers, opts, printMarkdownTable)
+	}
+	printMarkdownTable(w, fields, getResultRows(report.Results, printers))
+}
+
+func printMarkdownTable(w io.Writer, fields []string, rows [][]string) {
+	separators := make([]string, len(fields))
+	for i := range separators {
+		separators[i] = "---"
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "| %s |\n", strings.Join(fields, " | "))
+	fmt.Fprintf(w, "| %s |\n", strings.Join(separators, " | "))
+	for _, row := range rows {
+		fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | "))
+	}
+	fmt.Fprintln(w)
+}
+
+// convert duration to a string of whole nanoseconds
+func formatNs(d time.Duration) string {
+	return strconv.FormatInt(int64(d), 10)
+}
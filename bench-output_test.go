@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func getTestReport() *Report {
+	return &Report{
+		Version: reportVersion,
+		Input: ReportInput{
+			Source: "test",
+			Size:   1000,
+		},
+		Parameters: map[string]string{"count": "2"},
+		Results: []*BenchmarkResult{
+			{
+				Name:           "gzip",
+				CompressTime:   1500 * time.Nanosecond,
+				DecompressTime: 500 * time.Nanosecond,
+				InputSize:      1000,
+				CompressedSize: 250,
+				Ratio:          0.25,
+				Iterations:     2,
+				TotalStats:     &TimingStats{Min: 1900, Max: 2100, Mean: 2000},
+			},
+			{
+				Name:           "zstd",
+				CompressTime:   time.Microsecond,
+				DecompressTime: time.Microsecond,
+				InputSize:      1000,
+				CompressedSize: 200,
+				CompressSpeed:  1000,
+				Ratio:          0.2,
+				Messages:       &MessageStats{Count: 10, LatencyP50: 100, Overhead: 9},
+			},
+		},
+	}
+}
+
+func TestWriteJsonReport(t *testing.T) {
+	report := getTestReport()
+	var buf bytes.Buffer
+	if err := writeJsonReport(&buf, report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := &Report{}
+	if err := json.Unmarshal(buf.Bytes(), out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(out, report) {
+		t.Errorf("expected %+v but got %+v", report, out)
+	}
+	if !strings.Contains(buf.String(), `"compress_time_ns": 1500`) {
+		t.Errorf("expected times in nanoseconds but got %s", buf.String())
+	}
+}
+
+func TestWriteCsvReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeCsvReport(&buf, getTestReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows but got %d", len(rows))
+	}
+	if !reflect.DeepEqual(rows[0], csvHeader) {
+		t.Errorf("expected header %v but got %v", csvHeader, rows[0])
+	}
+	get := func(row int, column string) string {
+		return rows[row][slices.Index(csvHeader, column)]
+	}
+	tests := []struct {
+		row    int
+		column string
+		exp    string
+	}{
+		{1, "name", "gzip"},
+		{1, "input_size", "1000"},
+		{1, "total_time_ns", "2000"},
+		{1, "ratio", "0.25"},
+		{2, "compress_mb_per_sec", "1000"},
+		{1, "total_mean_ns", "2000"},
+		{1, "messages", ""},
+		{2, "total_mean_ns", ""},
+		{2, "messages", "10"},
+		{2, "overhead", "9"},
+	}
+	for _, test := range tests {
+		if out := get(test.row, test.column); out != test.exp {
+			t.Errorf("expected %s of row %d to be '%s' but got '%s'", test.column, test.row, test.exp, out)
+		}
+	}
+}
+
+func TestWriteReportToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.md")
+	output := &OutputOptions{Format: outputMarkdown, File: path}
+	if err := writeReport(output, getTestReport(), make([]byte, 1000), &PrintOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(out), "| Compression-Library | Total-Time | Compressed-Size | Ratio |") {
+		t.Errorf("expected a markdown table but got %s", out)
+	}
+}
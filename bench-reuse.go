@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+// resetWriter is an encoder which can be pointed at a new destination instead of being recreated
+type resetWriter interface {
+	io.WriteCloser
+	Reset(io.Writer)
+}
+
+// ReusableCodec holds an encoder and decoder which are reset for every payload, as production code
+// typically does, instead of being created inside the timed region
+type ReusableCodec struct {
+	Name    string
+	Encoder resetWriter
+	// resets the decoder to read from r and returns it
+	ResetDecoder func(r io.Reader) (io.Reader, error)
+	// if set, used instead of the encoder and decoder for codecs which compress a whole buffer at once
+	Compress   func([]byte) ([]byte, error)
+	Decompress func([]byte) ([]byte, error)
+	// if set, releases the encoder and decoder, such as the goroutines of a zstd decoder, once
+	// the codec is no longer used
+	Close func()
+}
+
+// releases the resources of the codec, if it holds any
+func (codec *ReusableCodec) release() {
+	if codec.Close != nil {
+		codec.Close()
+	}
+}
+
+// Reuser is implemented by Benchmarkers whose encoder and decoder can be reused between payloads
+type Reuser interface {
+	NewReusable() (*ReusableCodec, error)
+}
+
+// ReusingBenchmarker implements the Benchmarker interface using a ReusableCodec
+type ReusingBenchmarker struct {
+	// the Benchmarker the codec was created from
+	base      Benchmarker
+	codec     *ReusableCodec
+	setupTime time.Duration
+	buf       bytes.Buffer
+}
+
+// creates the encoder and decoder of the given Benchmarker up front, timing how long that takes
+func NewReusingBenchmarker(benchmarker Benchmarker) (*ReusingBenchmarker, error) {
+	reuser, ok := benchmarker.(Reuser)
+	if !ok {
+		return nil, fmt.Errorf("codec %T does not support reusing encoders", benchmarker)
+	}
+	t0 := time.Now()
+	codec, err := reuser.NewReusable()
+	if err != nil {
+		return nil, err
+	}
+	return &ReusingBenchmarker{
+		base:      benchmarker,
+		codec:     codec,
+		setupTime: time.Since(t0),
+	}, nil
+}
+
+func (rb *ReusingBenchmarker) RunBenchmark(input []byte, opts *RunOptions) (*BenchmarkResult, error) {
+	res, err := runRoundTrip(rb.codec.Name, input, rb.compress, rb.decompress, opts)
+	if err != nil {
+		return nil, err
+	}
+	res.SetupTime = rb.setupTime
+	return res, nil
+}
+
+// releases the reused encoder and decoder once every payload has been benchmarked
+func (rb *ReusingBenchmarker) Close() {
+	rb.codec.release()
+}
+
+func (rb *ReusingBenchmarker) compress(input []byte) ([]byte, time.Duration, error) {
+	if rb.codec.Compress != nil {
+		t0 := time.Now()
+		out, err := rb.codec.Compress(input)
+		return out, time.Since(t0), err
+	}
+	rb.buf.Reset()
+	t0 := time.Now()
+	rb.codec.Encoder.Reset(&rb.buf)
+	_, err := rb.codec.Encoder.Write(input)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := rb.codec.Encoder.Close(); err != nil {
+		return nil, 0, err
+	}
+	compTime := time.Since(t0)
+	// the buffer is reused by the next payload, so the caller gets its own copy
+	return bytes.Clone(rb.buf.Bytes()), compTime, nil
+}
+
+func (rb *ReusingBenchmarker) decompress(inputBytes []byte) ([]byte, time.Duration, error) {
+	if rb.codec.Decompress != nil {
+		t0 := time.Now()
+		out, err := rb.codec.Decompress(inputBytes)
+		return out, time.Since(t0), err
+	}
+	t0 := time.Now()
+	zr, err := rb.codec.ResetDecoder(bytes.NewReader(inputBytes))
+	if err != nil {
+		return nil, 0, err
+	}
+	outBytes, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, 0, err
+	}
+	return outBytes, time.Since(t0), nil
+}
+
+// closes every Benchmarker which holds on to resources between payloads
+func closeBenchmarkers(benchmarkers []Benchmarker) {
+	for _, benchmarker := range benchmarkers {
+		if closer, ok := benchmarker.(interface{ Close() }); ok {
+			closer.Close()
+		}
+	}
+}
+
+// wraps every Benchmarker so that its encoder and decoder are reused between payloads
+func newReusingBenchmarkers(benchmarkers []Benchmarker) ([]Benchmarker, error) {
+	out := make([]Benchmarker, 0, len(benchmarkers))
+	for _, benchmarker := range benchmarkers {
+		reusing, err := NewReusingBenchmarker(benchmarker)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, reusing)
+	}
+	return out, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReusingBenchmarker(t *testing.T) {
+	inputs := [][]byte{
+		[]byte("the quick brown fox jumps over the lazy dog"),
+		bytes.Repeat([]byte("bencomp "), 1000),
+		[]byte("a"),
+	}
+	// codec specs which configure the codec differently from its defaults are also reused
+	names := append(RegisteredCodecNames(), "zstd:mode=all", "zstd:concurrency=1:window=64KiB:crc=false")
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			specs, err := parseCodecSpecs(name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			benchmarkers, err := newBenchmarkers(specs)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			rb, err := NewReusingBenchmarker(benchmarkers[0])
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// run every input twice to make sure the encoder and decoder reset cleanly
+			for _, input := range append(inputs, inputs...) {
+				compressed, _, err := rb.compress(input)
+				if err != nil {
+					t.Fatalf("unexpected error while compressing: %v", err)
+				}
+				output, _, err := rb.decompress(compressed)
+				if err != nil {
+					t.Fatalf("unexpected error while decompressing: %v", err)
+				}
+				if !bytes.Equal(output, input) {
+					t.Fatalf("decompressed output does not match input of size %d", len(input))
+				}
+			}
+		})
+	}
+}
+
+func TestReusingBenchmarkerUnsupported(t *testing.T) {
+	if _, err := NewReusingBenchmarker(&TestBenchmarker{}); err == nil {
+		t.Errorf("expected error, but did not get one")
+	}
+}
+
+// TestBenchmarker implements the Benchmarker interface without supporting reuse
+type TestBenchmarker struct {
+}
+
+func (tb *TestBenchmarker) RunBenchmark(input []byte, opts *RunOptions) (*BenchmarkResult, error) {
+	return &BenchmarkResult{Name: "test"}, nil
+}
+
+// closeCountingReuser implements the Reuser interface, counting how many of its codecs are closed
+type closeCountingReuser struct {
+	TestBenchmarker
+	created, closed int
+}
+
+func (cr *closeCountingReuser) NewReusable() (*ReusableCodec, error) {
+	cr.created++
+	return &ReusableCodec{
+		Name: "test",
+		Close: func() {
+			cr.closed++
+		},
+	}, nil
+}
+
+func TestCloseBenchmarkers(t *testing.T) {
+	reuser := &closeCountingReuser{}
+	reusing, err := NewReusingBenchmarker(reuser)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	benchmarkers, err := newConcurrentBenchmarkers([]Benchmarker{reusing}, &ConcurrencyOptions{Workers: []int{1, 2}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	closeBenchmarkers(benchmarkers)
+	if reuser.created != 4 || reuser.closed != reuser.created {
+		t.Errorf("expected all 4 codecs to be closed, but %d of %d were", reuser.closed, reuser.created)
+	}
+}
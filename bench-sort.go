@@ -0,0 +1,129 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+const (
+	sortName            = "name"
+	sortCompressTime    = "compress-time"
+	sortDecompressTime  = "decompress-time"
+	sortTotalTime       = "total-time"
+	sortSetupTime       = "setup-time"
+	sortSize            = "size"
+	sortRatio           = "ratio"
+	sortCompressSpeed   = "compress-speed"
+	sortDecompressSpeed = "decompress-speed"
+	sortCPUTime         = "cpu-time"
+	sortCompressAlloc   = "compress-alloc"
+	sortDecompressAlloc = "decompress-alloc"
+	sortPeakHeap        = "peak-heap"
+	sortAggregateSpeed  = "aggregate-speed"
+	sortLatencyP50      = "latency-p50"
+	sortLatencyP99      = "latency-p99"
+)
+
+var (
+	// metrics the results can be sorted by, in the order they are listed to the user
+	sortKeys = []string{
+		sortName, sortCompressTime, sortDecompressTime, sortTotalTime, sortSetupTime,
+		sortSize, sortRatio, sortCompressSpeed, sortDecompressSpeed, sortCPUTime,
+		sortCompressAlloc, sortDecompressAlloc, sortPeakHeap, sortAggregateSpeed,
+		sortLatencyP50, sortLatencyP99,
+	}
+
+	// compares two results so that the better one comes first
+	sortFuncs = map[string]func(a, b *BenchmarkResult) int{
+		sortName: func(a, b *BenchmarkResult) int {
+			return strings.Compare(a.Name, b.Name)
+		},
+		sortCompressTime: func(a, b *BenchmarkResult) int {
+			return cmp.Compare(a.CompressTime, b.CompressTime)
+		},
+		sortDecompressTime: func(a, b *BenchmarkResult) int {
+			return cmp.Compare(a.DecompressTime, b.DecompressTime)
+		},
+		sortTotalTime: func(a, b *BenchmarkResult) int {
+			return cmp.Compare(a.GetTotalTime(), b.GetTotalTime())
+		},
+		sortSetupTime: func(a, b *BenchmarkResult) int {
+			return cmp.Compare(a.SetupTime, b.SetupTime)
+		},
+		sortSize: func(a, b *BenchmarkResult) int {
+			return cmp.Compare(a.CompressedSize, b.CompressedSize)
+		},
+		sortRatio: func(a, b *BenchmarkResult) int {
+			return cmp.Compare(a.Ratio, b.Ratio)
+		},
+		sortCompressSpeed: func(a, b *BenchmarkResult) int {
+			return cmp.Compare(b.CompressSpeed, a.CompressSpeed)
+		},
+		sortDecompressSpeed: func(a, b *BenchmarkResult) int {
+			return cmp.Compare(b.DecompressSpeed, a.DecompressSpeed)
+		},
+		sortCPUTime: func(a, b *BenchmarkResult) int {
+			return cmp.Compare(a.GetTotalCPUTime(), b.GetTotalCPUTime())
+		},
+		sortCompressAlloc: func(a, b *BenchmarkResult) int {
+			return cmp.Compare(allocBytes(a.CompressMemory), allocBytes(b.CompressMemory))
+		},
+		sortDecompressAlloc: func(a, b *BenchmarkResult) int {
+			return cmp.Compare(allocBytes(a.DecompressMemory), allocBytes(b.DecompressMemory))
+		},
+		sortPeakHeap: func(a, b *BenchmarkResult) int {
+			return cmp.Compare(peakHeap(a), peakHeap(b))
+		},
+		sortAggregateSpeed: func(a, b *BenchmarkResult) int {
+			return cmp.Compare(aggregateSpeed(b), aggregateSpeed(a))
+		},
+		sortLatencyP50: func(a, b *BenchmarkResult) int {
+			return cmp.Compare(messageStats(a).LatencyP50, messageStats(b).LatencyP50)
+		},
+		sortLatencyP99: func(a, b *BenchmarkResult) int {
+			return cmp.Compare(messageStats(a).LatencyP99, messageStats(b).LatencyP99)
+		},
+	}
+)
+
+// the metrics below are only measured with some flags, and are 0 for results without them
+
+func allocBytes(stats *MemoryStats) int {
+	if stats == nil {
+		return 0
+	}
+	return stats.AllocBytes
+}
+
+// returns the larger peak heap growth of compression and decompression
+func peakHeap(br *BenchmarkResult) int {
+	if br.CompressMemory == nil || br.DecompressMemory == nil {
+		return 0
+	}
+	return max(br.CompressMemory.PeakHeap, br.DecompressMemory.PeakHeap)
+}
+
+func aggregateSpeed(br *BenchmarkResult) float64 {
+	if br.Concurrency == nil {
+		return 0
+	}
+	return br.Concurrency.Throughput
+}
+
+func messageStats(br *BenchmarkResult) *MessageStats {
+	if br.Messages == nil {
+		return &MessageStats{}
+	}
+	return br.Messages
+}
+
+// sorts the results best first by the given metric, keeping the codec order for ties.
+// Does nothing if key is empty
+func sortResults(results []*BenchmarkResult, key string) {
+	sortFunc, ok := sortFuncs[key]
+	if !ok {
+		return
+	}
+	slices.SortStableFunc(results, sortFunc)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortResults(t *testing.T) {
+	type testData struct {
+		key      string
+		expNames []string
+	}
+	tests := []testData{
+		{key: "", expNames: []string{"zstd", "gzip", "s2", "snappy"}},
+		{key: sortName, expNames: []string{"gzip", "s2", "snappy", "zstd"}},
+		{key: sortTotalTime, expNames: []string{"s2", "snappy", "zstd", "gzip"}},
+		{key: sortRatio, expNames: []string{"zstd", "gzip", "snappy", "s2"}},
+		{key: sortCompressSpeed, expNames: []string{"s2", "snappy", "zstd", "gzip"}},
+		{key: sortCPUTime, expNames: []string{"s2", "gzip", "snappy", "zstd"}},
+		{key: sortCompressAlloc, expNames: []string{"s2", "snappy", "gzip", "zstd"}},
+		{key: sortPeakHeap, expNames: []string{"snappy", "s2", "gzip", "zstd"}},
+		{key: sortAggregateSpeed, expNames: []string{"s2", "snappy", "zstd", "gzip"}},
+		{key: sortLatencyP99, expNames: []string{"snappy", "s2", "zstd", "gzip"}},
+		// ties keep the codec order
+		{key: sortSetupTime, expNames: []string{"zstd", "gzip", "s2", "snappy"}},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			results := []*BenchmarkResult{
+				{Name: "zstd", CompressTime: 30, DecompressTime: 10, Ratio: 0.3, CompressSpeed: 30, CompressCPUTime: 90,
+					CompressMemory: &MemoryStats{AllocBytes: 400, PeakHeap: 300}, DecompressMemory: &MemoryStats{PeakHeap: 500},
+					Concurrency: &ConcurrencyStats{Throughput: 60}, Messages: &MessageStats{LatencyP99: 30}},
+				{Name: "gzip", CompressTime: 40, DecompressTime: 20, Ratio: 0.35, CompressSpeed: 20, CompressCPUTime: 40,
+					CompressMemory: &MemoryStats{AllocBytes: 300, PeakHeap: 200}, DecompressMemory: &MemoryStats{PeakHeap: 100},
+					Concurrency: &ConcurrencyStats{Throughput: 40}, Messages: &MessageStats{LatencyP99: 40}},
+				{Name: "s2", CompressTime: 10, DecompressTime: 5, Ratio: 0.5, CompressSpeed: 100, CompressCPUTime: 10,
+					CompressMemory: &MemoryStats{AllocBytes: 100, PeakHeap: 150}, DecompressMemory: &MemoryStats{PeakHeap: 50},
+					Concurrency: &ConcurrencyStats{Throughput: 200}, Messages: &MessageStats{LatencyP99: 10}},
+				{Name: "snappy", CompressTime: 15, DecompressTime: 5, Ratio: 0.45, CompressSpeed: 70, CompressCPUTime: 45,
+					CompressMemory: &MemoryStats{AllocBytes: 200, PeakHeap: 100}, DecompressMemory: &MemoryStats{PeakHeap: 100},
+					Concurrency: &ConcurrencyStats{Throughput: 140}, Messages: &MessageStats{LatencyP99: 5}},
+			}
+			sortResults(results, test.key)
+			names := make([]string, 0, len(results))
+			for _, result := range results {
+				names = append(names, result.Name)
+			}
+			if !reflect.DeepEqual(names, test.expNames) {
+				t.Errorf("expected %v but got %v", test.expNames, names)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	statMin    = "min"
+	statMax    = "max"
+	statMean   = "mean"
+	statStdDev = "stddev"
+	statP50    = "p50"
+	statP90    = "p90"
+	statP99    = "p99"
+	statCI     = "ci"
+)
+
+var (
+	// statistics which can be displayed as extra columns, in display order
+	statNames = []string{statMin, statMax, statMean, statStdDev, statP50, statP90, statP99, statCI}
+
+	// two-sided 95% critical values of Student's t-distribution, indexed by degrees of freedom - 1
+	tCritical95 = []float64{
+		12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+		2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+		2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+	}
+)
+
+// TimingStats summarizes the timings of repeated runs of the same codec
+type TimingStats struct {
+	Min    time.Duration `json:"min_ns"`
+	Max    time.Duration `json:"max_ns"`
+	Mean   time.Duration `json:"mean_ns"`
+	StdDev time.Duration `json:"stddev_ns"`
+	P50    time.Duration `json:"p50_ns"`
+	P90    time.Duration `json:"p90_ns"`
+	P99    time.Duration `json:"p99_ns"`
+	// half width of the 95% confidence interval of the mean
+	CI95 time.Duration `json:"ci95_ns"`
+}
+
+// calculates statistics over the given times, which do not need to be sorted
+func newTimingStats(times []time.Duration) *TimingStats {
+	n := len(times)
+	if n == 0 {
+		return &TimingStats{}
+	}
+	sorted := slices.Clone(times)
+	slices.Sort(sorted)
+	var sum float64
+	for _, t := range sorted {
+		sum += float64(t)
+	}
+	mean := sum / float64(n)
+	var stdDev, ci float64
+	if n > 1 {
+		var sqDiff float64
+		for _, t := range sorted {
+			diff := float64(t) - mean
+			sqDiff += diff * diff
+		}
+		// sample standard deviation
+		stdDev = math.Sqrt(sqDiff / float64(n-1))
+		ci = tCritical(n-1) * stdDev / math.Sqrt(float64(n))
+	}
+	return &TimingStats{
+		Min:    sorted[0],
+		Max:    sorted[n-1],
+		Mean:   time.Duration(mean),
+		StdDev: time.Duration(stdDev),
+		P50:    percentileTime(sorted, 50),
+		P90:    percentileTime(sorted, 90),
+		P99:    percentileTime(sorted, 99),
+		CI95:   time.Duration(ci),
+	}
+}
+
+// runningStats keeps the mean and variance of times as they are added, with Welford's algorithm,
+// so that they can be checked after every run without going over every time again
+type runningStats struct {
+	n    int
+	mean float64
+	// sum of the squared differences from the mean
+	m2 float64
+}
+
+func (rs *runningStats) add(t time.Duration) {
+	rs.n++
+	diff := float64(t) - rs.mean
+	rs.mean += diff / float64(rs.n)
+	rs.m2 += diff * (float64(t) - rs.mean)
+}
+
+// returns the half width of the 95% confidence interval of the mean
+func (rs *runningStats) ci95() float64 {
+	if rs.n < 2 {
+		return 0
+	}
+	// sample standard deviation
+	stdDev := math.Sqrt(rs.m2 / float64(rs.n-1))
+	return tCritical(rs.n-1) * stdDev / math.Sqrt(float64(rs.n))
+}
+
+// returns the two-sided 95% critical value for the given degrees of freedom
+func tCritical(df int) float64 {
+	if df <= 0 {
+		return 0
+	}
+	if df <= len(tCritical95) {
+		return tCritical95[df-1]
+	}
+	// close enough to the normal distribution
+	return 1.96
+}
+
+// returns the p-th percentile of sorted times using the nearest-rank method
+func percentileTime(sorted []time.Duration, p float64) time.Duration {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(float64(n)*p/100.0)) - 1
+	rank = max(0, min(rank, n-1))
+	return sorted[rank]
+}
+
+// returns the value of the named statistic
+func (ts *TimingStats) get(stat string) string {
+	switch stat {
+	case statMin:
+		return ts.Min.String()
+	case statMax:
+		return ts.Max.String()
+	case statMean:
+		return ts.Mean.String()
+	case statStdDev:
+		return ts.StdDev.String()
+	case statP50:
+		return ts.P50.String()
+	case statP90:
+		return ts.P90.String()
+	case statP99:
+		return ts.P99.String()
+	case statCI:
+		return fmt.Sprintf("±%s", ts.CI95)
+	default:
+		return ""
+	}
+}
+
+// returns the column header of the named statistic
+func statHeader(stat string) string {
+	switch stat {
+	case statStdDev:
+		return "Total-StdDev"
+	case statCI:
+		return "Total-CI95"
+	default:
+		return "Total-" + strings.ToUpper(stat[:1]) + stat[1:]
+	}
+}
+
+// parses a comma separated list of statistics, returning them in display order
+func parseStats(s string) ([]string, error) {
+	selected := map[string]bool{}
+	for _, stat := range strings.Split(s, ",") {
+		stat = strings.ToLower(strings.TrimSpace(stat))
+		if stat == "" {
+			continue
+		}
+		if stat == "all" {
+			return statNames, nil
+		}
+		if !slices.Contains(statNames, stat) {
+			return nil, fmt.Errorf("unknown statistic '%s', must be one of: all, %s", stat, strings.Join(statNames, ", "))
+		}
+		selected[stat] = true
+	}
+	stats := []string{}
+	for _, stat := range statNames {
+		if selected[stat] {
+			stats = append(stats, stat)
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimingStats(t *testing.T) {
+	type testData struct {
+		name  string
+		input []time.Duration
+		exp   TimingStats
+	}
+	tests := []testData{
+		{
+			name:  "single run",
+			input: []time.Duration{100},
+			exp: TimingStats{
+				Min:  100,
+				Max:  100,
+				Mean: 100,
+				P50:  100,
+				P90:  100,
+				P99:  100,
+			},
+		},
+		{
+			name:  "unsorted runs",
+			input: []time.Duration{400, 100, 300, 200},
+			exp: TimingStats{
+				Min:    100,
+				Max:    400,
+				Mean:   250,
+				StdDev: 129, // sqrt(50000 / 3)
+				P50:    200,
+				P90:    400,
+				P99:    400,
+				CI95:   205, // 3.182 * 129.099 / 2
+			},
+		},
+		{
+			name:  "empty",
+			input: []time.Duration{},
+			exp:   TimingStats{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := newTimingStats(test.input)
+			if *out != test.exp {
+				t.Errorf("expected %+v but got %+v", test.exp, *out)
+			}
+		})
+	}
+}
+
+func TestRunningStats(t *testing.T) {
+	times := []time.Duration{120, 80, 100, 95, 105, 130}
+	running := &runningStats{}
+	for _, d := range times {
+		running.add(d)
+	}
+	// the running stats keep the mean and confidence interval of all the times
+	exp := newTimingStats(times)
+	if time.Duration(running.mean) != exp.Mean || time.Duration(running.ci95()) != exp.CI95 {
+		t.Errorf("expected mean %v and ci %v but got %v and %v", exp.Mean, exp.CI95, running.mean, running.ci95())
+	}
+	if ci := (&runningStats{n: 1, mean: 100}).ci95(); ci != 0 {
+		t.Errorf("expected no confidence interval from a single time but got %v", ci)
+	}
+}
+
+func TestParseStats(t *testing.T) {
+	stats, err := parseStats("p99, MIN,ci")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp := []string{statMin, statP99, statCI}
+	if len(stats) != len(exp) {
+		t.Fatalf("expected %v but got %v", exp, stats)
+	}
+	for i := range exp {
+		if stats[i] != exp[i] {
+			t.Errorf("expected %v but got %v", exp, stats)
+		}
+	}
+	if stats, err := parseStats("all"); err != nil || len(stats) != len(statNames) {
+		t.Errorf("expected all statistics but got %v, %v", stats, err)
+	}
+	if _, err := parseStats("median"); err == nil {
+		t.Errorf("expected error, but did not get one")
+	}
+}
+
+func TestFindMedianFloat64(t *testing.T) {
+	// ratios which differ by less than 1 must still be ordered correctly
+	out := findMedianFloat64([]float64{0.53, 0.51, 0.52})
+	if out != 0.52 {
+		t.Errorf("expected 0.52 but got %v", out)
+	}
+}
+
+func TestPercentileTime(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i + 1)
+	}
+	for _, p := range []float64{50, 90, 99, 100} {
+		if out := percentileTime(sorted, p); out != time.Duration(p) {
+			t.Errorf("expected p%v to be %d but got %d", p, time.Duration(p), out)
+		}
+	}
+	if out := percentileTime([]time.Duration{7}, 99); out != 7 {
+		t.Errorf("expected 7 but got %d", out)
+	}
+	if out := percentileTime(nil, 50); out != 0 {
+		t.Errorf("expected 0 but got %d", out)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// StreamOptions control how the input is streamed through each codec
+type StreamOptions struct {
+	// size of each write to the encoder and each read from the decoder
+	ChunkSize int
+	// number of bytes of random JSON to generate, unused when streaming a file
+	Size int
+}
+
+// benchmarks every codec by streaming the input through it, repeating each run count times after
+// warmup discarded runs. Returns the size of the input along with one aggregated result per codec
+func runStreamBenchmark(cmd *cobra.Command, benchmarkers []Benchmarker, count, warmup int, opts *StreamOptions, runOpts *RunOptions) (int, []*BenchmarkResult, error) {
+	// stdin can only be streamed through a single codec once, rather than read again for every run
+	if filename, _ := cmd.Flags().GetString(fileInputFlag); filename == stdinFile && len(benchmarkers)*(warmup+count) > 1 {
+		return 0, nil, fmt.Errorf("error while preparing benchmark: streaming stdin requires a single codec and a single run")
+	}
+	nResults := make([][]*BenchmarkResult, 0, count)
+	for run := range warmup + count {
+		results := make([]*BenchmarkResult, len(benchmarkers))
+		for i, benchmarker := range benchmarkers {
+			source, err := openStreamSource(cmd, opts, run)
+			if err != nil {
+				return 0, nil, fmt.Errorf("error while preparing benchmark: %v", err)
+			}
+			result, err := runStream(benchmarker, source, opts.ChunkSize, runOpts)
+			source.Close()
+			if err != nil {
+				return 0, nil, fmt.Errorf("error while running benchmark: %v", err)
+			}
+			results[i] = result
+		}
+		if run < warmup {
+			continue
+		}
+		nResults = append(nResults, results)
+	}
+	aggResults := aggregateResults(nResults)
+	if len(aggResults) == 0 {
+		return 0, aggResults, nil
+	}
+	return aggResults[0].InputSize, aggResults, nil
+}
+
+// opens the input file, or a generator of random JSON documents. Every codec streams the same
+// documents in a run, while each run generates new ones
+func openStreamSource(cmd *cobra.Command, opts *StreamOptions, run int) (io.ReadCloser, error) {
+	if isRand, _ := cmd.Flags().GetBool(isRandInput); isRand {
+		jsonConfig, err := getJsonGenConfig(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup random JSON generator: %v", err)
+		}
+		jsonConfig.Seed += int64(run)
+		return &jsonStreamReader{
+			generator: newJsonGenerator(jsonConfig),
+			remaining: opts.Size,
+		}, nil
+	}
+	filename, err := cmd.Flags().GetString(fileInputFlag)
+	if err != nil {
+		return nil, err
+	}
+	if filename == stdinFile {
+		return io.NopCloser(cmd.InOrStdin()), nil
+	}
+	return os.Open(filename)
+}
+
+// jsonStreamReader reads newline separated random JSON documents up to a total size
+type jsonStreamReader struct {
+	generator JsonGenerator
+	remaining int
+	buf       []byte
+}
+
+func (jr *jsonStreamReader) Read(p []byte) (int, error) {
+	if jr.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if len(jr.buf) == 0 {
+		randJson, err := jr.generator.JsonGenerate()
+		if err != nil {
+			return 0, fmt.Errorf("failed to generate random JSON: %v", err)
+		}
+		doc, err := json.Marshal(randJson)
+		if err != nil {
+			return 0, err
+		}
+		jr.buf = append(doc, '\n')
+	}
+	n := copy(p[:min(len(p), jr.remaining)], jr.buf)
+	jr.buf = jr.buf[n:]
+	jr.remaining -= n
+	return n, nil
+}
+
+func (jr *jsonStreamReader) Close() error {
+	return nil
+}
+
+// returns the reusable encoder and decoder of a Benchmarker, which every codec streams through.
+// Codecs which compress whole buffers at once, such as zstd with mode=all, can not be streamed
+func getStreamCodec(benchmarker Benchmarker) (*ReusableCodec, time.Duration, error) {
+	if reusing, ok := benchmarker.(*ReusingBenchmarker); ok {
+		if reusing.codec.Compress != nil {
+			return nil, 0, fmt.Errorf("%s compresses whole buffers and can not be streamed", reusing.codec.Name)
+		}
+		return reusing.codec, reusing.setupTime, nil
+	}
+	reuser, ok := benchmarker.(Reuser)
+	if !ok {
+		return nil, 0, fmt.Errorf("codec %T does not support streaming", benchmarker)
+	}
+	t0 := time.Now()
+	codec, err := reuser.NewReusable()
+	if err != nil {
+		return nil, 0, err
+	}
+	setupTime := time.Since(t0)
+	if codec.Compress != nil {
+		codec.release()
+		return nil, 0, fmt.Errorf("%s compresses whole buffers and can not be streamed", codec.Name)
+	}
+	return codec, setupTime, nil
+}
+
+// compresses the source into a temporary file one chunk at a time, then decompresses the file
+// one chunk at a time, so that neither the input nor the output has to fit in memory. Reading the
+// source is neither timed nor metered, since it may be generating the input, and neither is writing
+// the temporary file, so that the disk is not charged to compression. Reading it back is part of decompression,
+// since some decoders read ahead on their own goroutines, but it was just written and is usually
+// still cached in memory. The round trip is verified with a checksum of the input and output
+func runStream(benchmarker Benchmarker, source io.Reader, chunkSize int, opts *RunOptions) (*BenchmarkResult, error) {
+	codec, setupTime, err := getStreamCodec(benchmarker)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := benchmarker.(*ReusingBenchmarker); !ok {
+		// the codec was created for this run alone, the reused one is closed with its Benchmarker
+		defer codec.release()
+	}
+	tmp, err := os.CreateTemp("", "bencomp-stream-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	result := &BenchmarkResult{
+		Name:      codec.Name,
+		SetupTime: setupTime,
+	}
+	inputHash := crc32.NewIEEE()
+	chunk := make([]byte, chunkSize)
+	var meter *memoryMeter
+	if opts.MeasureMemory {
+		meter = startMemoryMeter()
+	}
+	// the sampler keeps running until the meter is stopped, so it is stopped on every return
+	defer func() {
+		meter.Stop()
+	}()
+	// the encoder writes into memory, and what it wrote is moved to the file after each timed write
+	var compressed bytes.Buffer
+	out := &countingWriter{w: &compressed}
+	codec.Encoder.Reset(out)
+	for {
+		// the memory used to read the source, which may be generating it, is not charged to compression
+		meter.Pause()
+		n, readErr := io.ReadFull(source, chunk)
+		if n > 0 {
+			inputHash.Write(chunk[:n])
+			result.InputSize += n
+			meter.Resume()
+			cpu0 := processCPUTime()
+			t0 := time.Now()
+			if _, err := codec.Encoder.Write(chunk[:n]); err != nil {
+				return nil, err
+			}
+			result.CompressTime += time.Since(t0)
+			result.CompressCPUTime += processCPUTime() - cpu0
+			meter.Pause()
+			if _, err := compressed.WriteTo(tmp); err != nil {
+				return nil, err
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+	meter.Resume()
+	cpu0 := processCPUTime()
+	t0 := time.Now()
+	if err := codec.Encoder.Close(); err != nil {
+		return nil, err
+	}
+	result.CompressTime += time.Since(t0)
+	result.CompressCPUTime += processCPUTime() - cpu0
+	result.CompressMemory = meter.Stop()
+	if _, err := compressed.WriteTo(tmp); err != nil {
+		return nil, err
+	}
+	result.CompressedSize = out.n
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if opts.MeasureMemory {
+		meter = startMemoryMeter()
+	}
+	outputHash := crc32.NewIEEE()
+	outputSize := 0
+	cpu0 = processCPUTime()
+	t0 = time.Now()
+	decoder, err := codec.ResetDecoder(bufio.NewReaderSize(tmp, chunkSize))
+	if err != nil {
+		return nil, err
+	}
+	result.DecompressTime += time.Since(t0)
+	result.DecompressCPUTime += processCPUTime() - cpu0
+	for {
+		cpu0 := processCPUTime()
+		t0 := time.Now()
+		n, readErr := io.ReadFull(decoder, chunk)
+		result.DecompressTime += time.Since(t0)
+		result.DecompressCPUTime += processCPUTime() - cpu0
+		outputHash.Write(chunk[:n])
+		outputSize += n
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+	result.DecompressMemory = meter.Stop()
+	if outputSize != result.InputSize || outputHash.Sum32() != inputHash.Sum32() {
+		return nil, fmt.Errorf("%s: round trip failed, decompressed stream of %d bytes does not match the input of %d bytes",
+			codec.Name, outputSize, result.InputSize)
+	}
+	if result.InputSize > 0 {
+		result.Ratio = float64(result.CompressedSize) / float64(result.InputSize)
+	}
+	result.setThroughput()
+	return result, nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += n
+	return n, err
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestRunStream(t *testing.T) {
+	input := bytes.Repeat([]byte("bencomp streams its input in chunks\n"), 1000)
+	for _, name := range RegisteredCodecNames() {
+		t.Run(name, func(t *testing.T) {
+			specs, err := parseCodecSpecs(name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			benchmarkers, err := newBenchmarkers(specs)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// a chunk size that does not divide the input leaves a short final chunk
+			result, err := runStream(benchmarkers[0], bytes.NewReader(input), 1000, NewRunOptions())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.InputSize != len(input) {
+				t.Errorf("expected input size %d, got %d", len(input), result.InputSize)
+			}
+			if result.CompressedSize == 0 || result.CompressedSize >= len(input) {
+				t.Errorf("unexpected compressed size %d for input of size %d", result.CompressedSize, len(input))
+			}
+		})
+	}
+}
+
+func TestRunStreamUnsupported(t *testing.T) {
+	if _, err := runStream(&TestBenchmarker{}, bytes.NewReader([]byte("a")), 1, NewRunOptions()); err == nil {
+		t.Errorf("expected error, but did not get one")
+	}
+	// zstd with mode=all compresses whole buffers, which would be labelled as streamed
+	zopts, err := parseZstdOptions(map[string]string{"mode": zstdModeAll})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	zstder := NewZstdRunner(zstd.SpeedDefault, nil, zopts)
+	reusing, err := NewReusingBenchmarker(zstder)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reusing.Close()
+	for _, benchmarker := range []Benchmarker{zstder, reusing} {
+		if _, err := runStream(benchmarker, bytes.NewReader([]byte("a")), 1, NewRunOptions()); err == nil {
+			t.Errorf("expected error for %T, but did not get one", benchmarker)
+		}
+	}
+}
+
+func TestJsonStreamReader(t *testing.T) {
+	for _, size := range []int{0, 1, 10, 1000} {
+		reader := &jsonStreamReader{
+			generator: &TestJsonGenerator{},
+			remaining: size,
+		}
+		output, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(output) != size {
+			t.Errorf("expected %d bytes, got %d", size, len(output))
+		}
+	}
+}
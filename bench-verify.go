@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// returns an error naming the codec and the offset of the first differing byte if the output of a
+// round trip does not match its input. Every byte is compared, which is no slower than checksumming
+// both, and happens after the timed regions of compression and decompression
+func verifyRoundTrip(name string, input, output []byte) error {
+	if bytes.Equal(input, output) {
+		return nil
+	}
+	return fmt.Errorf("%s: round trip failed, decompressed output of %d bytes differs from the input of %d bytes at offset %d",
+		name, len(output), len(input), firstMismatch(input, output))
+}
+
+// returns the offset of the first byte which differs between a and b, or the length of the
+// shorter one if it is a prefix of the other
+func firstMismatch(a, b []byte) int {
+	n := min(len(a), len(b))
+	for i := range n {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
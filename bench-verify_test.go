@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		output    string
+		expOffset string
+	}{
+		{
+			name:   "equal",
+			input:  "bencomp",
+			output: "bencomp",
+		},
+		{
+			name:      "different byte",
+			input:     "bencomp",
+			output:    "bencoMp",
+			expOffset: "offset 5",
+		},
+		{
+			name:      "truncated",
+			input:     "bencomp",
+			output:    "ben",
+			expOffset: "offset 3",
+		},
+		{
+			name:      "empty output",
+			input:     "bencomp",
+			expOffset: "offset 0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := verifyRoundTrip("codec", []byte(test.input), []byte(test.output))
+			if test.expOffset == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error, but did not get one")
+			}
+			if !strings.HasPrefix(err.Error(), "codec:") || !strings.Contains(err.Error(), test.expOffset) {
+				t.Errorf("expected error naming the codec and %s, got: %v", test.expOffset, err)
+			}
+		})
+	}
+}
+
+func TestRunRoundTripMismatch(t *testing.T) {
+	compress := func(b []byte) ([]byte, time.Duration, error) {
+		return b, 0, nil
+	}
+	corrupt := func(b []byte) ([]byte, time.Duration, error) {
+		out := []byte(string(b))
+		out[2] ^= 1
+		return out, 0, nil
+	}
+	_, err := runRoundTrip("broken", []byte("bencomp"), compress, corrupt, NewRunOptions())
+	if err == nil {
+		t.Fatalf("expected error, but did not get one")
+	}
+	if !strings.Contains(err.Error(), "broken") || !strings.Contains(err.Error(), "offset 2") {
+		t.Errorf("expected error naming the codec and offset 2, got: %v", err)
+	}
+}
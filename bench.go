@@ -1,10 +1,11 @@
 package main
 
 import (
-	"compress/zlib"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"slices"
 	"strings"
 	"text/tabwriter"
@@ -21,8 +22,19 @@ type PrintOptions struct {
 	ShouldPrintInput bool
 	ShouldPrintCTime bool
 	ShouldPrintDTime bool
+	ShowThroughput   bool
+	ShowMemory       bool
+	ShowCPUTime      bool
+	ShowConcurrency  bool
+	ShowScaling      bool
 	NetworkSpeed     uint64
 	NetworkPayloads  int
+	ShowMessages     bool
+	ShowSetupTime    bool
+	Stats            []string
+	ShowIterations   bool
+	// print the results of every file of a corpus, not just those of every extension
+	ShowFiles bool
 }
 
 func NewBenchCmd() *cobra.Command {
@@ -33,8 +45,8 @@ func NewBenchCmd() *cobra.Command {
 reports performance statistics.
 
 You can choose to run the benchmark against a specific file or randomly
-generate a JSON input with a variey of parameters. The benchmark runs gzip,
-3 different levels of zlib, and zstd.`,
+generate a JSON input with a variey of parameters. By default the benchmark
+runs gzip, 3 different levels of zlib, and zstd; use --codecs to choose.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := runBenchmark(cmd)
 			if err != nil {
@@ -45,6 +57,7 @@ generate a JSON input with a variey of parameters. The benchmark runs gzip,
 	}
 	root.CompletionOptions.DisableDefaultCmd = true
 	createBenchFlags(root)
+	root.AddCommand(NewCompareCmd())
 	return root
 }
 
@@ -55,40 +68,211 @@ func runBenchmark(cmd *cobra.Command) error {
 	if err != nil {
 		return fmt.Errorf("error while preparing benchmark: %v", err)
 	}
-	nResults := make([][]*BenchmarkResult, 0, count)
+	output, err := getOutputFlags(cmd)
+	if err != nil {
+		return fmt.Errorf("error while preparing benchmark: %v", err)
+	}
+	runOpts := NewRunOptions()
+	runOpts.MeasureMemory = printOptions.ShowMemory
+	if err := setRandomSeed(cmd); err != nil {
+		return fmt.Errorf("error while preparing benchmark: %v", err)
+	}
+	stream, err := getStreamFlags(cmd)
+	if err != nil {
+		return fmt.Errorf("error while preparing benchmark: %v", err)
+	}
+	// a streamed stdin is read while streaming instead
+	src, err := newInputSource(cmd, stream == nil)
+	if err != nil {
+		return fmt.Errorf("error while preparing benchmark: %v", err)
+	}
+	benchmarkers, zdict, err := getBenchmarkers(cmd, src)
+	if err != nil {
+		return fmt.Errorf("error while preparing benchmark: %v", err)
+	}
+	defer closeBenchmarkers(benchmarkers)
+	warmup, err := getWarmupFlag(cmd)
+	if err != nil {
+		return fmt.Errorf("error while preparing benchmark: %v", err)
+	}
+	adaptive, err := getAdaptiveFlags(cmd)
+	if err != nil {
+		return fmt.Errorf("error while preparing benchmark: %v", err)
+	}
+	corpusOpts, err := getCorpusFlags(cmd)
+	if err != nil {
+		return fmt.Errorf("error while preparing benchmark: %v", err)
+	}
 	var input []byte
-	for _ = range count {
-		input, err = getBenchmarkInput(cmd)
+	var aggResults []*BenchmarkResult
+	var corpus *CorpusReport
+	inputSize := 0
+	if corpusOpts != nil {
+		files, err := readCorpus(corpusOpts)
 		if err != nil {
 			return fmt.Errorf("error while preparing benchmark: %v", err)
 		}
-		if len(input) == 0 {
-			return fmt.Errorf("error while preparing benchmark: there is nothing to compress")
+		inputSize, aggResults, corpus, err = runCorpusBenchmark(benchmarkers, files, count, warmup, runOpts)
+		if err != nil {
+			return fmt.Errorf("error while running benchmark: %v", err)
+		}
+	} else if stream != nil {
+		inputSize, aggResults, err = runStreamBenchmark(cmd, benchmarkers, count, warmup, stream, runOpts)
+		if err != nil {
+			return err
 		}
-		benchmarkers := getBenchmarkers()
-		results := make([]*BenchmarkResult, len(benchmarkers))
-		for i, benchmarker := range benchmarkers {
-			result, err := benchmarker.RunBenchmark(input)
+	} else if adaptive != nil {
+		input, aggResults, err = runAdaptiveBenchmark(src, benchmarkers, warmup, adaptive, runOpts)
+		if err != nil {
+			return err
+		}
+	} else {
+		nResults := make([][]*BenchmarkResult, 0, count)
+		for run := range warmup + count {
+			var messages [][]byte
+			input, messages, err = getCheckedBenchmarkMessages(src)
 			if err != nil {
-				return fmt.Errorf("error while running benchmark: %v", err)
+				return err
+			}
+			results := make([]*BenchmarkResult, len(benchmarkers))
+			for i, benchmarker := range benchmarkers {
+				result, err := runBenchmarker(benchmarker, input, messages, runOpts)
+				if err != nil {
+					return fmt.Errorf("error while running benchmark: %v", err)
+				}
+				results[i] = result
+			}
+			if run < warmup {
+				// warmup results are thrown away
+				continue
 			}
-			results[i] = result
+			nResults = append(nResults, results)
 		}
-		nResults = append(nResults, results)
+		aggResults = aggregateResults(nResults)
+	}
+	if stream == nil && corpus == nil {
+		inputSize = len(input)
+	}
+	setConcurrencyScaling(aggResults)
+	sortResults(aggResults, output.SortBy)
+	report := newReport(cmd, inputSize, aggResults, zdict)
+	if stream != nil {
+		report.Input.ChunkSize = stream.ChunkSize
+	}
+	if corpus != nil {
+		sortCorpusResults(corpus, output.SortBy)
+		report.Corpus = corpus
+	}
+	if err := writeReport(output, report, input, printOptions); err != nil {
+		return fmt.Errorf("error while writing results: %v", err)
 	}
-	aggResults := aggregateResults(nResults)
-	printResults(input, printOptions, aggResults)
 	return nil
 }
 
-func getBenchmarkers() []Benchmarker {
-	return []Benchmarker{
-		NewGzipRunner(),
-		NewZlibRunner(zlib.DefaultCompression),
-		NewZlibRunner(zlib.BestCompression),
-		NewZlibRunner(zlib.BestSpeed),
-		NewZstdRunner(),
+// gets the input and messages for a single run, failing if there is nothing to compress
+func getCheckedBenchmarkMessages(src *inputSource) ([]byte, [][]byte, error) {
+	input, messages, err := getBenchmarkMessages(src)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while preparing benchmark: %v", err)
 	}
+	if len(input) == 0 || (messages != nil && len(messages) == 0) {
+		return nil, nil, fmt.Errorf("error while preparing benchmark: there is nothing to compress")
+	}
+	return input, messages, nil
+}
+
+// runs a single benchmark, compressing each message independently if there are any
+func runBenchmarker(benchmarker Benchmarker, input []byte, messages [][]byte, opts *RunOptions) (*BenchmarkResult, error) {
+	if messages != nil {
+		return runMessageBenchmark(benchmarker, messages, opts)
+	}
+	return benchmarker.RunBenchmark(input, opts)
+}
+
+// returns the codecs selected by the user, or the default set if none were selected.
+// If the user asked for a zstd dictionary, it is trained here and returned alongside
+// the dictionary-compressed codecs. If the user asked to reuse encoders or run several
+// workers at once, they are created here
+func getBenchmarkers(cmd *cobra.Command, src *inputSource) ([]Benchmarker, *ZstdDict, error) {
+	specs, err := getCodecsFlag(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	benchmarkers, err := newBenchmarkers(specs)
+	if err != nil {
+		return nil, nil, err
+	}
+	samples, dictSize, err := getZstdDictSamples(cmd, src)
+	if err != nil {
+		return nil, nil, err
+	}
+	var zdict *ZstdDict
+	if samples != nil {
+		zdict, err = trainZstdDict(samples, dictSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		dictBenchmarkers, err := newZstdDictBenchmarkers(specs, zdict)
+		if err != nil {
+			return nil, nil, err
+		}
+		benchmarkers = append(benchmarkers, dictBenchmarkers...)
+	}
+	if reuse, _ := cmd.Flags().GetBool(reuseFlag); reuse {
+		benchmarkers, err = newReusingBenchmarkers(benchmarkers)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	concurrency, err := getConcurrencyFlags(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	if concurrency != nil {
+		// every worker compresses its own random document, rather than a rotated copy of the first
+		if isRand, _ := cmd.Flags().GetBool(isRandInput); isRand && concurrency.Distinct {
+			concurrency.WorkerInputs = src.getWorkerInputs
+		}
+		benchmarkers, err = newConcurrentBenchmarkers(benchmarkers, concurrency)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return benchmarkers, zdict, nil
+}
+
+// gets the samples used to train a zstd dictionary, either generated or read from files.
+// Returns nil samples if the user did not ask for a dictionary
+func getZstdDictSamples(cmd *cobra.Command, src *inputSource) ([][]byte, int, error) {
+	numSamples, dictFiles, dictSize, err := getZstdDictFlags(cmd)
+	if err != nil {
+		return nil, 0, err
+	}
+	var samples [][]byte
+	if dictFiles != "" {
+		filenames, err := filepath.Glob(dictFiles)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid argument for %s: %v", zstdDictFilesFlag, err)
+		}
+		if len(filenames) == 0 {
+			return nil, 0, fmt.Errorf("invalid argument for %s: no files match '%s'", zstdDictFilesFlag, dictFiles)
+		}
+		for _, filename := range filenames {
+			sample, err := os.ReadFile(filename)
+			if err != nil {
+				return nil, 0, fmt.Errorf("error while reading dictionary sample: %v", err)
+			}
+			samples = append(samples, sample)
+		}
+	}
+	for range numSamples {
+		sample, err := getRandInput(src)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error while generating dictionary sample: %v", err)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, dictSize, nil
 }
 
 func aggregateResults(nResults [][]*BenchmarkResult) []*BenchmarkResult {
@@ -100,38 +284,72 @@ func aggregateResults(nResults [][]*BenchmarkResult) []*BenchmarkResult {
 	final := make([]*BenchmarkResult, 0, m)
 	for libraryID := range m {
 		// for each compression library
-		libName := nResults[0][libraryID].Name
-		compTimes := make([]time.Duration, n)
-		decompTimes := make([]time.Duration, n)
-		sizes := make([]int, n)
-		ratios := make([]float64, n)
+		runs := make([]*BenchmarkResult, n)
 		for resultIndex, results := range nResults {
-			result := results[libraryID]
-			compTimes[resultIndex] = result.CompressTime
-			decompTimes[resultIndex] = result.DecompressTime
-			sizes[resultIndex] = result.CompressedSize
-			ratios[resultIndex] = result.Ratio
-		}
-		medCompTime := findMedianTime(compTimes)
-		medDecompTime := findMedianTime(decompTimes)
-		medSize := findMedianInt(sizes)
-		medRatio := findMedianFloat64(ratios)
-		libraryAgg := &BenchmarkResult{
-			Name:           libName,
-			CompressTime:   medCompTime,
-			DecompressTime: medDecompTime,
-			CompressedSize: medSize,
-			Ratio:          medRatio,
+			runs[resultIndex] = results[libraryID]
 		}
-		final = append(final, libraryAgg)
+		final = append(final, aggregateRuns(runs))
 	}
 	return final
 }
 
+// combines repeated runs of the same compression library into a single result of median values
+func aggregateRuns(runs []*BenchmarkResult) *BenchmarkResult {
+	n := len(runs)
+	compTimes := make([]time.Duration, n)
+	decompTimes := make([]time.Duration, n)
+	totalTimes := make([]time.Duration, n)
+	compCPUTimes := make([]time.Duration, n)
+	decompCPUTimes := make([]time.Duration, n)
+	inputSizes := make([]int, n)
+	sizes := make([]int, n)
+	ratios := make([]float64, n)
+	messageStats := make([]*MessageStats, n)
+	compMems := make([]*MemoryStats, n)
+	concurrencyStats := make([]*ConcurrencyStats, n)
+	decompMems := make([]*MemoryStats, n)
+	for resultIndex, result := range runs {
+		compTimes[resultIndex] = result.CompressTime
+		decompTimes[resultIndex] = result.DecompressTime
+		totalTimes[resultIndex] = result.GetTotalTime()
+		compCPUTimes[resultIndex] = result.CompressCPUTime
+		decompCPUTimes[resultIndex] = result.DecompressCPUTime
+		inputSizes[resultIndex] = result.InputSize
+		sizes[resultIndex] = result.CompressedSize
+		ratios[resultIndex] = result.Ratio
+		messageStats[resultIndex] = result.Messages
+		compMems[resultIndex] = result.CompressMemory
+		concurrencyStats[resultIndex] = result.Concurrency
+		decompMems[resultIndex] = result.DecompressMemory
+	}
+	compStats := newTimingStats(compTimes)
+	decompStats := newTimingStats(decompTimes)
+	totalStats := newTimingStats(totalTimes)
+	result := &BenchmarkResult{
+		Name:              runs[0].Name,
+		CompressTime:      findMedianTime(compTimes),
+		DecompressTime:    findMedianTime(decompTimes),
+		CompressCPUTime:   findMedianTime(compCPUTimes),
+		DecompressCPUTime: findMedianTime(decompCPUTimes),
+		InputSize:         findMedianInt(inputSizes),
+		CompressedSize:    findMedianInt(sizes),
+		Ratio:             findMedianFloat64(ratios),
+		SetupTime:         runs[0].SetupTime,
+		Messages:          aggregateMessageStats(messageStats),
+		CompressMemory:    aggregateMemoryStats(compMems),
+		DecompressMemory:  aggregateMemoryStats(decompMems),
+		Concurrency:       aggregateConcurrencyStats(concurrencyStats),
+		Iterations:        n,
+		CompressStats:     compStats,
+		DecompressStats:   decompStats,
+		TotalStats:        totalStats,
+	}
+	result.setThroughput()
+	return result
+}
+
 func findMedianTime(times []time.Duration) time.Duration {
-	slices.SortFunc(times, func(a, b time.Duration) int {
-		return int(a - b)
-	})
+	slices.Sort(times)
 	n := len(times)
 	if n%2 == 0 {
 		return (times[(n/2)-1] + times[n/2]) / 2
@@ -140,9 +358,7 @@ func findMedianTime(times []time.Duration) time.Duration {
 }
 
 func findMedianInt(ints []int) int {
-	slices.SortFunc(ints, func(a, b int) int {
-		return a - b
-	})
+	slices.Sort(ints)
 	n := len(ints)
 	if n%2 == 0 {
 		return (ints[(n/2)-1] + ints[n/2]) / 2
@@ -151,9 +367,7 @@ func findMedianInt(ints []int) int {
 }
 
 func findMedianFloat64(floats []float64) float64 {
-	slices.SortFunc(floats, func(a, b float64) int {
-		return int(a - b)
-	})
+	slices.Sort(floats)
 	n := len(floats)
 	if n%2 == 0 {
 		return (floats[(n/2)-1] + floats[n/2]) / 2
@@ -161,21 +375,47 @@ func findMedianFloat64(floats []float64) float64 {
 	return floats[n/2]
 }
 
-func printResults(input []byte, opts *PrintOptions, results []*BenchmarkResult) {
-	tw := tabwriter.NewWriter(os.Stdout, 2, 2, 4, ' ', 0)
-	printers := printResultHeader(tw, input, opts)
-	for _, result := range results {
-		printResultRow(tw, result, printers)
+func printResults(w io.Writer, report *Report, input []byte, opts *PrintOptions) {
+	printInputSummary(w, report, input, opts)
+	fields, printers := getResultColumns(opts)
+	if report.Corpus != nil {
+		printCorpusTables(w, report.Corpus, fields, printers, opts, printTextTable)
+	}
+	printTextTable(w, fields, getResultRows(report.Results, printers))
+}
+
+// prints a table aligned with spaces
+func printTextTable(w io.Writer, fields []string, rows [][]string) {
+	tw := tabwriter.NewWriter(w, 2, 2, 4, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(fields, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
 	}
 	tw.Flush()
 }
 
-// prints the top row of the result table, and returns a list of formatting functions for all other rows
-func printResultHeader(tw *tabwriter.Writer, input []byte, opts *PrintOptions) []func(*BenchmarkResult) string {
+// prints the lines describing the input which come before the result table
+func printInputSummary(w io.Writer, report *Report, input []byte, opts *PrintOptions) {
 	if opts.ShouldPrintInput {
-		fmt.Printf("Input data: %v\n", input)
+		fmt.Fprintf(w, "Input data: %v\n", input)
+	}
+	fmt.Fprintf(w, "Original data size: %s\n", formatBytes(report.Input.Size))
+	if report.Input.Seed != nil {
+		fmt.Fprintf(w, "Random seed: %d\n", *report.Input.Seed)
+	}
+	if report.Input.Messages > 0 {
+		fmt.Fprintf(w, "Messages: %d, compressed independently\n", report.Input.Messages)
 	}
-	fmt.Printf("Original data size: %s\n", formatBytes(len(input)))
+	if report.Input.ChunkSize > 0 {
+		fmt.Fprintf(w, "Streamed in chunks of %s\n", formatBytes(report.Input.ChunkSize))
+	}
+	if report.Corpus != nil {
+		fmt.Fprintf(w, "Files: %d, with %d extensions\n", len(report.Corpus.Files), len(report.Corpus.Extensions))
+	}
+}
+
+// returns the header of each column of the result table, and a list of formatting functions for the rows
+func getResultColumns(opts *PrintOptions) ([]string, []func(*BenchmarkResult) string) {
 	fields := []string{"Compression-Library"}
 	printers := []func(*BenchmarkResult) string{
 		func(br *BenchmarkResult) string {
@@ -194,10 +434,102 @@ func printResultHeader(tw *tabwriter.Writer, input []byte, opts *PrintOptions) [
 			return br.DecompressTime.String()
 		})
 	}
+	if opts.ShowSetupTime {
+		fields = append(fields, "Setup-Time")
+		printers = append(printers, func(br *BenchmarkResult) string {
+			return br.SetupTime.String()
+		})
+	}
+	if opts.ShowIterations {
+		fields = append(fields, "Iterations")
+		printers = append(printers, func(br *BenchmarkResult) string {
+			return fmt.Sprintf("%d", br.Iterations)
+		})
+	}
 	fields = append(fields, "Total-Time")
 	printers = append(printers, func(br *BenchmarkResult) string {
 		return br.GetTotalTime().String()
 	})
+	for _, stat := range opts.Stats {
+		fields = append(fields, statHeader(stat))
+		printers = append(printers, func(br *BenchmarkResult) string {
+			return br.TotalStats.get(stat)
+		})
+	}
+	if opts.ShowThroughput {
+		fields = append(fields, "Compression-Speed", "Decompression-Speed")
+		printers = append(printers,
+			func(br *BenchmarkResult) string {
+				return formatThroughput(br.CompressSpeed)
+			},
+			func(br *BenchmarkResult) string {
+				return formatThroughput(br.DecompressSpeed)
+			},
+		)
+	}
+	if opts.ShowConcurrency {
+		fields = append(fields, "Workers", "Wall-Time", "Aggregate-Speed", "CPU-Cores")
+		printers = append(printers,
+			func(br *BenchmarkResult) string {
+				return fmt.Sprintf("%d", br.Concurrency.Workers)
+			},
+			func(br *BenchmarkResult) string {
+				return br.Concurrency.WallTime.String()
+			},
+			func(br *BenchmarkResult) string {
+				return formatThroughput(br.Concurrency.Throughput)
+			},
+			func(br *BenchmarkResult) string {
+				return fmt.Sprintf("%.2f", br.Concurrency.getCPUUtilization())
+			},
+		)
+	}
+	if opts.ShowScaling {
+		fields = append(fields, "Scaling")
+		printers = append(printers, func(br *BenchmarkResult) string {
+			return fmt.Sprintf("%.2fx", br.Concurrency.Scaling)
+		})
+	}
+	if opts.ShowCPUTime {
+		fields = append(fields, "Compression-CPU-Time", "Decompression-CPU-Time", "CPU-Cores")
+		printers = append(printers,
+			func(br *BenchmarkResult) string {
+				return br.CompressCPUTime.String()
+			},
+			func(br *BenchmarkResult) string {
+				return br.DecompressCPUTime.String()
+			},
+			func(br *BenchmarkResult) string {
+				return fmt.Sprintf("%.2f", br.GetCPUUtilization())
+			},
+		)
+	}
+	if opts.ShowMemory {
+		fields = append(fields,
+			"Compression-Alloc", "Compression-Allocs", "Compression-Peak-Heap",
+			"Decompression-Alloc", "Decompression-Allocs", "Decompression-Peak-Heap",
+		)
+		printers = append(printers,
+			func(br *BenchmarkResult) string {
+				return formatBytes(br.CompressMemory.AllocBytes)
+			},
+			func(br *BenchmarkResult) string {
+				return fmt.Sprintf("%d", br.CompressMemory.Allocs)
+			},
+			func(br *BenchmarkResult) string {
+				return formatBytes(br.CompressMemory.PeakHeap)
+			},
+			func(br *BenchmarkResult) string {
+				return formatBytes(br.DecompressMemory.AllocBytes)
+			},
+			func(br *BenchmarkResult) string {
+				return fmt.Sprintf("%d", br.DecompressMemory.Allocs)
+			},
+			func(br *BenchmarkResult) string {
+				return formatBytes(br.DecompressMemory.PeakHeap)
+			},
+		)
+	}
 	fields = append(fields, "Compressed-Size")
 	printers = append(printers, func(br *BenchmarkResult) string {
 		return formatBytes(br.CompressedSize)
@@ -206,23 +538,43 @@ func printResultHeader(tw *tabwriter.Writer, input []byte, opts *PrintOptions) [
 	printers = append(printers, func(br *BenchmarkResult) string {
 		return formatRatio(br.Ratio)
 	})
+	if opts.ShowMessages {
+		fields = append(fields, "P50-Latency", "P90-Latency", "P99-Latency", "Overhead")
+		printers = append(printers,
+			func(br *BenchmarkResult) string {
+				return br.Messages.LatencyP50.String()
+			},
+			func(br *BenchmarkResult) string {
+				return br.Messages.LatencyP90.String()
+			},
+			func(br *BenchmarkResult) string {
+				return br.Messages.LatencyP99.String()
+			},
+			func(br *BenchmarkResult) string {
+				return formatBytes(br.Messages.Overhead)
+			},
+		)
+	}
 	if opts.NetworkSpeed != 0 {
 		fields = append(fields, fmt.Sprintf("%d-Payloads", opts.NetworkPayloads))
 		printers = append(printers, func(br *BenchmarkResult) string {
 			return br.GetBatchTime(opts.NetworkPayloads, opts.NetworkSpeed).String()
 		})
 	}
-	fmt.Fprintln(tw, strings.Join(fields, "\t"))
-	return printers
+	return fields, printers
 }
 
-func printResultRow(tw *tabwriter.Writer, result *BenchmarkResult, printers []func(*BenchmarkResult) string) {
-	entries := []string{}
-	for _, printer := range printers {
-		entries = append(entries, printer(result))
+// formats every result into a row of the result table
+func getResultRows(results []*BenchmarkResult, printers []func(*BenchmarkResult) string) [][]string {
+	rows := make([][]string, 0, len(results))
+	for _, result := range results {
+		entries := make([]string, 0, len(printers))
+		for _, printer := range printers {
+			entries = append(entries, printer(result))
+		}
+		rows = append(rows, entries)
 	}
-	outStr := strings.Join(entries, "\t")
-	fmt.Fprintln(tw, outStr)
+	return rows
 }
 
 // convert float64 to string
@@ -230,6 +582,11 @@ func formatRatio(r float64) string {
 	return fmt.Sprintf("%.2f%%", (r * 100.0))
 }
 
+// convert MB/s to string
+func formatThroughput(mbps float64) string {
+	return fmt.Sprintf("%.2f MB/s", mbps)
+}
+
 // convert file size to string
 func formatBytes(size int) string {
 	unitLadder := []string{"B", "KB", "MB", "GB"}
@@ -242,25 +599,82 @@ func formatBytes(size int) string {
 	return fmt.Sprintf("%.4f %s", f, unitLadder[unit])
 }
 
-// Gets input for compression and decompression
-func getBenchmarkInput(cmd *cobra.Command) ([]byte, error) {
+// inputSource is where the input of every run is read or generated from. Stdin can only be read
+// once, and every random document continues the random sequence of the one before it, so both are
+// set up once by runBenchmark and every run takes its input from here
+type inputSource struct {
+	cmd *cobra.Command
+	// only set when generating random JSON or dictionary samples
+	generator JsonGenerator
+	// only set when the input is read from stdin
+	stdin []byte
+	// the random inputs of the workers after the first with --distinct-inputs. They are generated
+	// the first time a codec needs them and kept until the input of the next run is generated, so
+	// that every codec compresses the same documents
+	workerInputs [][]byte
+}
+
+// sets up the input of the command. Stdin is only read if readStdin is set, so that it can be
+// streamed instead
+func newInputSource(cmd *cobra.Command, readStdin bool) (*inputSource, error) {
+	src := &inputSource{cmd: cmd}
 	isRand, err := cmd.Flags().GetBool(isRandInput)
+	if err != nil {
+		return nil, err
+	}
+	numSamples, _, _, err := getZstdDictFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	// dictionary samples are generated even when the input is read from a file
+	if isRand || numSamples > 0 {
+		jsonConfig, err := getJsonGenConfig(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup random JSON generator: %v", err)
+		}
+		src.generator = newJsonGenerator(jsonConfig)
+	}
+	if isRand || !readStdin {
+		return src, nil
+	}
+	filename, err := cmd.Flags().GetString(fileInputFlag)
+	if err != nil {
+		return nil, err
+	}
+	if filename == stdinFile {
+		src.stdin, err = io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return nil, fmt.Errorf("error while reading input from stdin: %v", err)
+		}
+	}
+	return src, nil
+}
+
+// Gets input for compression and decompression
+func getBenchmarkInput(src *inputSource) ([]byte, error) {
+	isRand, err := src.cmd.Flags().GetBool(isRandInput)
 	if err != nil {
 		return nil, fmt.Errorf("%v", err)
 	}
 	var input []byte
 	if isRand {
 		// user wants to randomly generate JSON input
-		input, err = getRandInput(cmd)
+		input, err = getRandInput(src)
 		if err != nil {
 			return nil, fmt.Errorf("error while generating random input: %v", err)
 		}
+		// the inputs of the workers belong to the previous run
+		src.workerInputs = nil
 	} else {
 		// user wants to read input from a file
-		filename, err := cmd.Flags().GetString(fileInputFlag)
+		filename, err := src.cmd.Flags().GetString(fileInputFlag)
 		if err != nil {
 			return nil, fmt.Errorf("%v", err)
 		}
+		if filename == stdinFile {
+			// stdin was read once up front, every run compresses the same input
+			return src.stdin, nil
+		}
 		input, err = os.ReadFile(filename)
 		if err != nil {
 			return nil, fmt.Errorf("error while reading input file: %v", err)
@@ -269,20 +683,48 @@ func getBenchmarkInput(cmd *cobra.Command) ([]byte, error) {
 	return input, nil
 }
 
-// randomly generates a JSON object according to user flags
-func getRandInput(cmd *cobra.Command) ([]byte, error) {
-	generator, err := getJsonGenerator(cmd)
+// returns the random inputs of n workers for the current run, generating the ones which are missing
+func (src *inputSource) getWorkerInputs(n int) ([][]byte, error) {
+	for len(src.workerInputs) < n {
+		input, err := getRandInput(src)
+		if err != nil {
+			return nil, fmt.Errorf("error while generating random input: %v", err)
+		}
+		src.workerInputs = append(src.workerInputs, input)
+	}
+	return src.workerInputs[:n], nil
+}
+
+// randomly generates a JSON object, or a batch of JSON records, according to user flags
+func getRandInput(src *inputSource) ([]byte, error) {
+	records, err := getRecordsFlags(src.cmd)
 	if err != nil {
-		return nil, fmt.Errorf("failed to setup random JSON generator: %v", err)
+		return nil, err
 	}
-	randJson, err := generator.JsonGenerate()
+	_, maxSize, err := getJsonSizeFlags(src.cmd)
+	if err != nil {
+		return nil, err
+	}
+	if records != nil {
+		return generateRecords(src.generator, records, maxSize)
+	}
+	randJson, err := src.generator.JsonGenerate()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate random JSON: %v", err)
 	}
-	return json.Marshal(randJson)
+	data, err := json.Marshal(randJson)
+	if err != nil {
+		return nil, err
+	}
+	// the tree of fields and children stops growing at the maximum size, other documents are checked here
+	if maxSize > 0 && len(data) > maxSize {
+		return nil, fmt.Errorf("failed to generate random JSON: the document of %s is larger than the maximum size of %s",
+			formatBytes(len(data)), formatBytes(maxSize))
+	}
+	return data, nil
 }
 
-func getJsonGenerator(cmd *cobra.Command) (JsonGenerator, error) {
+func getJsonGenConfig(cmd *cobra.Command) (*JsonGenConfig, error) {
 	numFieldMin, numFieldMax, err := getNumFields(cmd)
 	if err != nil {
 		return nil, err
@@ -305,5 +747,40 @@ func getJsonGenerator(cmd *cobra.Command) (JsonGenerator, error) {
 	jsonConfig.StrLenMax = maxStrLen
 	jsonConfig.DictFile = dictFile
 	jsonConfig.DictSize = dictSize
-	return newJsonGenerator(jsonConfig), nil
+	jsonConfig.Seed, err = cmd.Flags().GetInt64(seedFlag)
+	if err != nil {
+		return nil, err
+	}
+	jsonConfig.ValueTypes, err = getJsonTypesFlag(cmd)
+	if err != nil {
+		return nil, err
+	}
+	jsonConfig.ArrayLenMin, jsonConfig.ArrayLenMax, err = getArrayLenFlag(cmd)
+	if err != nil {
+		return nil, err
+	}
+	jsonConfig.Schema, err = getJsonSchemaFlag(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if jsonConfig.Schema != nil && len(jsonConfig.ValueTypes) > 0 {
+		return nil, fmt.Errorf("%s cannot be used with a schema or example document", randJsonTypesFlag)
+	}
+	records, err := getRecordsFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	// a schema already decides which keys every record has
+	jsonConfig.StableKeys = records != nil && jsonConfig.Schema == nil
+	jsonConfig.TargetSize, jsonConfig.MaxSize, err = getJsonSizeFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if jsonConfig.TargetSize > 0 && jsonConfig.Schema != nil {
+		return nil, fmt.Errorf("%s cannot be used with a schema or example document, use %s instead", targetSizeFlag, randJsonRecordsSizeFlag)
+	}
+	if jsonConfig.TargetSize > 0 && records != nil {
+		return nil, fmt.Errorf("%s cannot be used with records, use %s instead", targetSizeFlag, randJsonRecordsSizeFlag)
+	}
+	return jsonConfig, nil
 }
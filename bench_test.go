@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bytes"
+	"io"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -19,6 +23,22 @@ func (tgen *TestJsonGenerator) JsonGenerate() (*JsonElement, error) {
 }
 
 func testConfigEqual(t *testing.T, actual, exp *JsonGenConfig) {
+	// the seed is chosen at random unless the test sets one
+	if exp.Seed == 0 && actual != nil {
+		unseeded := *actual
+		unseeded.Seed = 0
+		actual = &unseeded
+	}
+	// the default maximum size applies unless the test sets one
+	if exp.MaxSize == 0 {
+		maxSize, err := parseByteSize(defaultJsonMaxSize, randJsonMaxSizeFlag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		limited := *exp
+		limited.MaxSize = int(maxSize)
+		exp = &limited
+	}
 	if !reflect.DeepEqual(actual, exp) {
 		t.Errorf("actual and expected configs are not equal, actual: %v, exp: %v", actual, exp)
 	}
@@ -28,10 +48,12 @@ func TestBenchCmd(t *testing.T) {
 	type testData struct {
 		name          string
 		args          []string
+		stdin         string
 		expConfig     JsonGenConfig
 		wantErr       bool
 		wantNilConfig bool
 	}
+	schemaFile := writeTestFile(t, "schema.json", testSchema)
 	tests := []testData{
 		{
 			name: "default",
@@ -46,6 +68,144 @@ func TestBenchCmd(t *testing.T) {
 				StrLenMax:        defaultJsonStrLen,
 			},
 		},
+		{
+			name: "seed",
+			args: []string{"--rand-gen", "--seed", "42"},
+			expConfig: JsonGenConfig{
+				FieldsPerNodeMin: defaultFieldNum,
+				FieldsPerNodeMax: defaultFieldNum,
+				DegreeMin:        defaultDegree,
+				DegreeMax:        defaultDegree,
+				DepthMax:         defaultMaxDepth,
+				StrLenMin:        defaultJsonStrLen,
+				StrLenMax:        defaultJsonStrLen,
+				Seed:             42,
+			},
+		},
+		{
+			name: "json types",
+			args: []string{"--rand-gen", "--json-types", "string=3,INT,null=0", "--json-array-len-range", "0-4"},
+			expConfig: JsonGenConfig{
+				FieldsPerNodeMin: defaultFieldNum,
+				FieldsPerNodeMax: defaultFieldNum,
+				DegreeMin:        defaultDegree,
+				DegreeMax:        defaultDegree,
+				DepthMax:         defaultMaxDepth,
+				StrLenMin:        defaultJsonStrLen,
+				StrLenMax:        defaultJsonStrLen,
+				ValueTypes: []JsonValueWeight{
+					{Type: jsonTypeString, Weight: 3},
+					{Type: jsonTypeInt, Weight: 1},
+					{Type: jsonTypeNull, Weight: 0},
+				},
+				ArrayLenMin: 0,
+				ArrayLenMax: 5,
+			},
+		},
+		{
+			name:    "unknown json type",
+			args:    []string{"--rand-gen", "--json-types", "string,date"},
+			wantErr: true,
+		},
+		{
+			name:    "repeated json type",
+			args:    []string{"--rand-gen", "--json-types", "int,int=2"},
+			wantErr: true,
+		},
+		{
+			name:    "zero json type weights",
+			args:    []string{"--rand-gen", "--json-types", "int=0"},
+			wantErr: true,
+		},
+		{
+			name: "json records",
+			args: []string{"--rand-gen", "--json-records", "100", "--json-records-format", "array"},
+			expConfig: JsonGenConfig{
+				FieldsPerNodeMin: defaultFieldNum,
+				FieldsPerNodeMax: defaultFieldNum,
+				DegreeMin:        defaultDegree,
+				DegreeMax:        defaultDegree,
+				DepthMax:         defaultMaxDepth,
+				StrLenMin:        defaultJsonStrLen,
+				StrLenMax:        defaultJsonStrLen,
+				StableKeys:       true,
+			},
+		},
+		{
+			name:    "json records count and size",
+			args:    []string{"--rand-gen", "--json-records", "100", "--json-records-size", "1MB"},
+			wantErr: true,
+		},
+		{
+			name:    "json records format without records",
+			args:    []string{"--rand-gen", "--json-records-format", "array"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown json records format",
+			args:    []string{"--rand-gen", "--json-records", "10", "--json-records-format", "csv"},
+			wantErr: true,
+		},
+		{
+			name:    "streamed json records",
+			args:    []string{"--rand-gen", "--json-records-size", "1MB", "--stream"},
+			wantErr: true,
+		},
+		{
+			name: "target size",
+			args: []string{"--rand-gen", "--target-size", "10MB", "--json-max-size", "20MB"},
+			expConfig: JsonGenConfig{
+				FieldsPerNodeMin: defaultFieldNum,
+				FieldsPerNodeMax: defaultFieldNum,
+				DegreeMin:        defaultDegree,
+				DegreeMax:        defaultDegree,
+				DepthMax:         defaultMaxDepth,
+				StrLenMin:        defaultJsonStrLen,
+				StrLenMax:        defaultJsonStrLen,
+				TargetSize:       10000000,
+				MaxSize:          20000000,
+			},
+		},
+		{
+			name:    "target size larger than max size",
+			args:    []string{"--rand-gen", "--target-size", "10MB", "--json-max-size", "1MB"},
+			wantErr: true,
+		},
+		{
+			name:    "target size with records",
+			args:    []string{"--rand-gen", "--target-size", "1MB", "--json-records", "10"},
+			wantErr: true,
+		},
+		{
+			name:    "target size with json schema",
+			args:    []string{"--rand-gen", "--target-size", "1MB", "--json-schema", schemaFile},
+			wantErr: true,
+		},
+		{
+			name:    "streamed target size",
+			args:    []string{"--rand-gen", "--target-size", "1MB", "--stream"},
+			wantErr: true,
+		},
+		{
+			name:    "json schema with json types",
+			args:    []string{"--rand-gen", "--json-schema", schemaFile, "--json-types", "int"},
+			wantErr: true,
+		},
+		{
+			name:    "missing json schema",
+			args:    []string{"--rand-gen", "--json-schema", "./testdata/missing.json"},
+			wantErr: true,
+		},
+		{
+			name:    "json schema and example",
+			args:    []string{"--rand-gen", "--json-schema", "./bench_test.go", "--json-example", "./bench_test.go"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid json example",
+			args:    []string{"--rand-gen", "--json-example", "./bench_test.go"},
+			wantErr: true,
+		},
 		{
 			name:          "file input test",
 			args:          []string{"--file", "./bench_test.go"},
@@ -232,6 +392,269 @@ func TestBenchCmd(t *testing.T) {
 			args:    []string{"--rand-gen", "--network-bandwidth", "100000 GB"},
 			wantErr: true,
 		},
+		{
+			name: "select codecs",
+			args: []string{"--rand-gen", "--codecs", "gzip,zlib:9"},
+			expConfig: JsonGenConfig{
+				FieldsPerNodeMin: defaultFieldNum,
+				FieldsPerNodeMax: defaultFieldNum,
+				DegreeMin:        defaultDegree,
+				DegreeMax:        defaultDegree,
+				DepthMax:         defaultMaxDepth,
+				StrLenMin:        defaultJsonStrLen,
+				StrLenMax:        defaultJsonStrLen,
+			},
+		},
+		{
+			name:    "unknown codec",
+			args:    []string{"--rand-gen", "--codecs", "gzip,foo"},
+			wantErr: true,
+		},
+		{
+			name: "zstd dictionary from files",
+			args: []string{"--rand-gen", "--zstd-dict-files", "./*.go", "--codecs", "zstd:1,gzip"},
+			expConfig: JsonGenConfig{
+				FieldsPerNodeMin: defaultFieldNum,
+				FieldsPerNodeMax: defaultFieldNum,
+				DegreeMin:        defaultDegree,
+				DegreeMax:        defaultDegree,
+				DepthMax:         defaultMaxDepth,
+				StrLenMin:        defaultJsonStrLen,
+				StrLenMax:        defaultJsonStrLen,
+			},
+		},
+		{
+			name:    "zstd dictionary no matching files",
+			args:    []string{"--rand-gen", "--zstd-dict-files", "./*.nothing"},
+			wantErr: true,
+		},
+		{
+			name:    "zstd dictionary negative samples",
+			args:    []string{"--rand-gen", "--zstd-dict-samples", "-1"},
+			wantErr: true,
+		},
+		{
+			name:    "zstd dictionary samples and files",
+			args:    []string{"--rand-gen", "--zstd-dict-samples", "10", "--zstd-dict-files", "./*.go"},
+			wantErr: true,
+		},
+		{
+			name: "messages json",
+			args: []string{"--rand-gen", "--messages", "json", "--message-count", "5"},
+			expConfig: JsonGenConfig{
+				FieldsPerNodeMin: defaultFieldNum,
+				FieldsPerNodeMax: defaultFieldNum,
+				DegreeMin:        defaultDegree,
+				DegreeMax:        defaultDegree,
+				DepthMax:         defaultMaxDepth,
+				StrLenMin:        defaultJsonStrLen,
+				StrLenMax:        defaultJsonStrLen,
+			},
+		},
+		{
+			name:          "messages by size",
+			args:          []string{"--file", "./bench_test.go", "--messages", "1KB"},
+			wantNilConfig: true,
+		},
+		{
+			name:          "messages by line",
+			args:          []string{"--file", "./bench_test.go", "--messages", "lines"},
+			wantNilConfig: true,
+		},
+		{
+			name:    "messages json from file",
+			args:    []string{"--file", "./bench_test.go", "--messages", "json"},
+			wantErr: true,
+		},
+		{
+			name:    "messages invalid",
+			args:    []string{"--file", "./bench_test.go", "--messages", "words"},
+			wantErr: true,
+		},
+		{
+			name:          "reuse encoders",
+			args:          []string{"--file", "./bench_test.go", "--reuse", "--messages", "lines", "--codecs", "gzip,zlib,zstd,s2"},
+			wantNilConfig: true,
+		},
+		{
+			name:          "adaptive",
+			args:          []string{"--file", "./bench_test.go", "--adaptive", "--adaptive-time", "10ms", "--codecs", "s2"},
+			wantNilConfig: true,
+		},
+		{
+			name:    "adaptive with count",
+			args:    []string{"--file", "./bench_test.go", "--adaptive", "--count", "3"},
+			wantErr: true,
+		},
+		{
+			name:    "adaptive invalid time",
+			args:    []string{"--file", "./bench_test.go", "--adaptive", "--adaptive-time", "0s"},
+			wantErr: true,
+		},
+		{
+			name:          "json output",
+			args:          []string{"--file", "./bench_test.go", "--output", "json", "--codecs", "s2"},
+			wantNilConfig: true,
+		},
+		{
+			name:          "csv output",
+			args:          []string{"--file", "./bench_test.go", "-o", "csv", "--count", "2", "--messages", "lines", "--codecs", "s2"},
+			wantNilConfig: true,
+		},
+		{
+			name:          "markdown output",
+			args:          []string{"--file", "./bench_test.go", "-o", "MARKDOWN", "--codecs", "s2"},
+			wantNilConfig: true,
+		},
+		{
+			name:          "save baseline",
+			args:          []string{"--file", "./bench_test.go", "--save", filepath.Join(t.TempDir(), "baseline.json"), "--codecs", "s2"},
+			wantNilConfig: true,
+		},
+		{
+			name:          "throughput sorted by speed",
+			args:          []string{"--file", "./bench_test.go", "--show-throughput", "--sort", "compress-speed", "--codecs", "s2,snappy"},
+			wantNilConfig: true,
+		},
+		{
+			name:          "memory",
+			args:          []string{"--file", "./bench_test.go", "--show-memory", "--count", "2", "--codecs", "gzip,zstd"},
+			wantNilConfig: true,
+		},
+		{
+			name:          "cpu time",
+			args:          []string{"--file", "./bench_test.go", "--show-cpu-time", "--messages", "lines", "--codecs", "zstd"},
+			wantNilConfig: true,
+		},
+		{
+			name:    "cpu time with memory",
+			args:    []string{"--file", "./bench_test.go", "--show-cpu-time", "--show-memory"},
+			wantErr: true,
+		},
+		{
+			name:          "concurrency",
+			args:          []string{"--file", "./bench_test.go", "--concurrency", "3", "--reuse", "--codecs", "gzip,zstd"},
+			wantNilConfig: true,
+		},
+		{
+			name:          "concurrency sweep",
+			args:          []string{"--file", "./bench_test.go", "--concurrency-sweep", "--distinct-inputs", "--count", "2", "--codecs", "s2"},
+			wantNilConfig: true,
+		},
+		{
+			name:    "concurrency with messages",
+			args:    []string{"--file", "./bench_test.go", "--concurrency", "2", "--messages", "lines"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid concurrency",
+			args:    []string{"--file", "./bench_test.go", "--concurrency", "0"},
+			wantErr: true,
+		},
+		{
+			name:          "stream file",
+			args:          []string{"--file", "./bench_test.go", "--stream", "--stream-chunk", "1KiB", "--show-memory", "--codecs", "gzip,zstd"},
+			wantNilConfig: true,
+		},
+		{
+			name: "stream random JSON",
+			args: []string{"--rand-gen", "--stream", "--stream-size", "64KiB", "--reuse", "--count", "2", "--codecs", "s2"},
+			expConfig: JsonGenConfig{
+				FieldsPerNodeMin: defaultFieldNum,
+				FieldsPerNodeMax: defaultFieldNum,
+				DegreeMin:        defaultDegree,
+				DegreeMax:        defaultDegree,
+				DepthMax:         defaultMaxDepth,
+				StrLenMin:        defaultJsonStrLen,
+				StrLenMax:        defaultJsonStrLen,
+			},
+		},
+		{
+			name:    "stream with messages",
+			args:    []string{"--file", "./bench_test.go", "--stream", "--messages", "lines"},
+			wantErr: true,
+		},
+		{
+			name:    "stream with concurrency",
+			args:    []string{"--file", "./bench_test.go", "--stream", "--concurrency", "2"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid stream chunk",
+			args:    []string{"--file", "./bench_test.go", "--stream", "--stream-chunk", "0"},
+			wantErr: true,
+		},
+		{
+			name:          "directory corpus",
+			args:          []string{"--dir", ".", "--max-file-size", "20KB", "--show-files", "--codecs", "s2", "-o", "markdown"},
+			wantNilConfig: true,
+		},
+		{
+			name:          "glob corpus",
+			args:          []string{"--glob", "./*_test.go", "--min-file-size", "1KB", "--codecs", "gzip,s2", "-o", "csv"},
+			wantNilConfig: true,
+		},
+		{
+			name:    "corpus with messages",
+			args:    []string{"--glob", "./*_test.go", "--messages", "lines"},
+			wantErr: true,
+		},
+		{
+			name:    "recursive without directory",
+			args:    []string{"--file", "./bench_test.go", "--recursive"},
+			wantErr: true,
+		},
+		{
+			name:    "file and directory",
+			args:    []string{"--file", "./bench_test.go", "--dir", "."},
+			wantErr: true,
+		},
+		{
+			name:          "stdin",
+			args:          []string{"--file", "-", "--count", "3", "--messages", "lines", "--codecs", "gzip,s2"},
+			stdin:         "first line\nsecond line\n",
+			wantNilConfig: true,
+		},
+		{
+			name:          "stream stdin",
+			args:          []string{"-f", "-", "--stream", "--codecs", "zstd"},
+			stdin:         "streamed from stdin",
+			wantNilConfig: true,
+		},
+		{
+			name:    "stream stdin with several codecs",
+			args:    []string{"-f", "-", "--stream", "--codecs", "zstd,s2"},
+			stdin:   "streamed from stdin",
+			wantErr: true,
+		},
+		{
+			name:    "empty stdin",
+			args:    []string{"-f", "-"},
+			wantErr: true,
+		},
+		{
+			name: "zstd options with dictionary",
+			args: []string{"--rand-gen", "--zstd-dict-files", "./*.go", "--codecs", "zstd:mode=all:crc=false"},
+			expConfig: JsonGenConfig{
+				FieldsPerNodeMin: defaultFieldNum,
+				FieldsPerNodeMax: defaultFieldNum,
+				DegreeMin:        defaultDegree,
+				DegreeMax:        defaultDegree,
+				DepthMax:         defaultMaxDepth,
+				StrLenMin:        defaultJsonStrLen,
+				StrLenMax:        defaultJsonStrLen,
+			},
+		},
+		{
+			name:    "invalid sort",
+			args:    []string{"--file", "./bench_test.go", "--sort", "speed"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid output",
+			args:    []string{"--file", "./bench_test.go", "-o", "xml"},
+			wantErr: true,
+		},
 		{
 			name:    "error no mode",
 			args:    []string{},
@@ -255,6 +678,7 @@ func TestBenchCmd(t *testing.T) {
 			outConfig = nil
 			cmd := NewBenchCmd()
 			cmd.SetArgs(test.args)
+			cmd.SetIn(strings.NewReader(test.stdin))
 			err := cmd.Execute()
 			if test.wantErr {
 				if err == nil {
@@ -272,3 +696,119 @@ func TestBenchCmd(t *testing.T) {
 		})
 	}
 }
+
+func TestInputSourceStdin(t *testing.T) {
+	cmd := NewBenchCmd()
+	if err := cmd.ParseFlags([]string{"--file", stdinFile}); err != nil {
+		t.Fatal(err)
+	}
+	cmd.SetIn(strings.NewReader("piped input"))
+	src, err := newInputSource(cmd, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// later runs get the same input rather than an empty stdin
+	for range 2 {
+		input, err := getBenchmarkInput(src)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(input) != "piped input" {
+			t.Errorf("expected 'piped input', got '%s'", input)
+		}
+	}
+	// stdin which is streamed is left unread
+	cmd.SetIn(strings.NewReader("streamed input"))
+	if _, err := newInputSource(cmd, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rest, _ := io.ReadAll(cmd.InOrStdin()); string(rest) != "streamed input" {
+		t.Errorf("expected stdin to be left unread, but %q is left", rest)
+	}
+}
+
+func TestInputSourceWorkerInputs(t *testing.T) {
+	// other tests mock the generator, which always generates the same document
+	mocked := newJsonGenerator
+	newJsonGenerator = NewJsonGenerator
+	t.Cleanup(func() {
+		newJsonGenerator = mocked
+	})
+	cmd := NewBenchCmd()
+	if err := cmd.ParseFlags([]string{"--rand-gen", "--seed", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	src, err := newInputSource(cmd, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := getBenchmarkInput(src); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// every codec of the run gets the same inputs, whatever its number of workers
+	first, err := src.getWorkerInputs(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := src.getWorkerInputs(3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(second) != 3 || !bytes.Equal(first[0], second[0]) || bytes.Equal(second[0], second[1]) {
+		t.Errorf("expected the first inputs to be kept and new ones to differ, got %q and %q", first, second)
+	}
+	// the next run gets new inputs
+	if _, err := getBenchmarkInput(src); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	next, err := src.getWorkerInputs(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Equal(first[0], next[0]) {
+		t.Errorf("expected the next run to get new worker inputs")
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	type testData struct {
+		input   string
+		exp     uint64
+		wantErr bool
+	}
+	tests := []testData{
+		{input: "", exp: 0},
+		{input: "1000", exp: 1000},
+		{input: "10B", exp: 10},
+		{input: "128KB", exp: 128000},
+		{input: "4kb", exp: 4000},
+		{input: "256MB", exp: 256000000},
+		{input: "10GB", exp: 10000000000},
+		{input: "4KiB", exp: 4096},
+		{input: "8mib", exp: 8 << 20},
+		{input: "1GiB", exp: 1 << 30},
+		{input: "10 B", wantErr: true},
+		{input: "KB", wantErr: true},
+		{input: "B", wantErr: true},
+		{input: "10TB", wantErr: true},
+		{input: "-1KB", wantErr: true},
+		{input: "100000000000GB", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			out, err := parseByteSize(test.input, "test")
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected error, but did not get one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out != test.exp {
+				t.Errorf("expected %d but got %d", test.exp, out)
+			}
+		})
+	}
+}
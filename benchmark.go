@@ -3,21 +3,144 @@ package main
 import "time"
 
 type Benchmarker interface {
-	RunBenchmark([]byte) (*BenchmarkResult, error)
+	RunBenchmark(input []byte, opts *RunOptions) (*BenchmarkResult, error)
+}
+
+// RunOptions control how every run of a codec is measured
+type RunOptions struct {
+	// set by --show-memory, measuring memory forces a garbage collection before every compression
+	// and decompression and samples the heap while they run, which slows them down slightly
+	MeasureMemory bool
+}
+
+func NewRunOptions() *RunOptions {
+	return &RunOptions{}
 }
 
 type BenchmarkResult struct {
-	Name           string
-	CompressTime   time.Duration
-	DecompressTime time.Duration
-	CompressedSize int
-	Ratio          float64
+	Name           string        `json:"name"`
+	CompressTime   time.Duration `json:"compress_time_ns"`
+	DecompressTime time.Duration `json:"decompress_time_ns"`
+	// user and system CPU time of the whole process, which is larger than the wall clock time if the
+	// codec uses several cores. When memory is measured it includes sampling the heap
+	CompressCPUTime   time.Duration `json:"compress_cpu_time_ns"`
+	DecompressCPUTime time.Duration `json:"decompress_cpu_time_ns"`
+	InputSize         int           `json:"input_size"`
+	CompressedSize    int           `json:"compressed_size"`
+	Ratio             float64       `json:"ratio"`
+	// MB/s of uncompressed data
+	CompressSpeed   float64       `json:"compress_mb_per_sec"`
+	DecompressSpeed float64       `json:"decompress_mb_per_sec"`
+	SetupTime       time.Duration `json:"setup_time_ns,omitempty"`
+	Messages        *MessageStats `json:"messages,omitempty"`
+	// only set when running several workers at once
+	Concurrency *ConcurrencyStats `json:"concurrency,omitempty"`
+	// only set when measuring memory
+	CompressMemory   *MemoryStats `json:"compress_memory,omitempty"`
+	DecompressMemory *MemoryStats `json:"decompress_memory,omitempty"`
+	// only set on results aggregated over repeated runs
+	Iterations      int          `json:"iterations,omitempty"`
+	CompressStats   *TimingStats `json:"compress_stats,omitempty"`
+	DecompressStats *TimingStats `json:"decompress_stats,omitempty"`
+	TotalStats      *TimingStats `json:"total_stats,omitempty"`
+}
+
+// a single timed compression or decompression pass over the input
+type codecFunc func([]byte) ([]byte, time.Duration, error)
+
+// the output and measurements of a single compression or decompression pass
+type codecRun struct {
+	out  []byte
+	time time.Duration
+	// CPU time of the whole process, so it includes any goroutines the codec started
+	cpuTime time.Duration
+	memory  *MemoryStats
+}
+
+// runs the codec function, also measuring its CPU time and, if the user asked for it, its memory use
+func runMeasured(codec codecFunc, input []byte, opts *RunOptions) (*codecRun, error) {
+	var meter *memoryMeter
+	if opts.MeasureMemory {
+		meter = startMemoryMeter()
+	}
+	// the sampler keeps running until the meter is stopped, even if the codec fails
+	defer meter.Stop()
+	cpu0 := processCPUTime()
+	out, t, err := codec(input)
+	cpuTime := processCPUTime() - cpu0
+	if err != nil {
+		return nil, err
+	}
+	return &codecRun{
+		out:     out,
+		time:    t,
+		cpuTime: cpuTime,
+		memory:  meter.Stop(),
+	}, nil
+}
+
+// compresses then decompresses input, checks that the output matches the input, and collects the
+// timings and size under the given name
+func runRoundTrip(name string, input []byte, compress, decompress codecFunc, opts *RunOptions) (*BenchmarkResult, error) {
+	comp, err := runMeasured(compress, input, opts)
+	if err != nil {
+		return nil, err
+	}
+	compSize := len(comp.out)
+	decomp, err := runMeasured(decompress, comp.out, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyRoundTrip(name, input, decomp.out); err != nil {
+		return nil, err
+	}
+	res := BenchmarkResult{
+		DecompressTime:    decomp.time,
+		CompressTime:      comp.time,
+		CompressCPUTime:   comp.cpuTime,
+		DecompressCPUTime: decomp.cpuTime,
+		InputSize:         len(input),
+		CompressedSize:    compSize,
+		Ratio:             float64(compSize) / float64(len(input)),
+		Name:              name,
+		CompressMemory:    comp.memory,
+		DecompressMemory:  decomp.memory,
+	}
+	res.setThroughput()
+	return &res, nil
+}
+
+// sets the compress and decompress speeds from the input size and times
+func (br *BenchmarkResult) setThroughput() {
+	br.CompressSpeed = throughput(br.InputSize, br.CompressTime)
+	br.DecompressSpeed = throughput(br.InputSize, br.DecompressTime)
+}
+
+// returns the number of MB processed per second
+func throughput(size int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(size) / 1e6 / d.Seconds()
 }
 
 func (br *BenchmarkResult) GetTotalTime() time.Duration {
 	return br.CompressTime + br.DecompressTime
 }
 
+func (br *BenchmarkResult) GetTotalCPUTime() time.Duration {
+	return br.CompressCPUTime + br.DecompressCPUTime
+}
+
+// returns the average number of cores kept busy while compressing and decompressing
+func (br *BenchmarkResult) GetCPUUtilization() float64 {
+	total := br.GetTotalTime()
+	if total <= 0 {
+		return 0
+	}
+	return float64(br.GetTotalCPUTime()) / float64(total)
+}
+
 func (br *BenchmarkResult) GetBatchTime(n int, speed uint64) time.Duration {
 	if speed == 0 {
 		return 0
@@ -67,3 +67,64 @@ func TestBatchTime(t *testing.T) {
 		})
 	}
 }
+
+func TestThroughput(t *testing.T) {
+	input := make([]byte, 2000000)
+	compress := func(b []byte) ([]byte, time.Duration, error) {
+		return b[:len(b)/2], time.Second, nil
+	}
+	decompress := func(b []byte) ([]byte, time.Duration, error) {
+		return input, 100 * time.Millisecond, nil
+	}
+	br, err := runRoundTrip("test", input, compress, decompress, NewRunOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if br.InputSize != len(input) {
+		t.Errorf("expected input size %d but got %d", len(input), br.InputSize)
+	}
+	if br.CompressSpeed != 2 {
+		t.Errorf("expected compress speed 2 MB/s but got %v", br.CompressSpeed)
+	}
+	if br.DecompressSpeed != 20 {
+		t.Errorf("expected decompress speed 20 MB/s but got %v", br.DecompressSpeed)
+	}
+	if speed := throughput(len(input), 0); speed != 0 {
+		t.Errorf("expected 0 MB/s for no time but got %v", speed)
+	}
+}
+
+func TestCPUTime(t *testing.T) {
+	if !cpuTimeSupported {
+		t.Skip("CPU time is not supported on this platform")
+	}
+	spin := func(b []byte) ([]byte, time.Duration, error) {
+		t0 := time.Now()
+		for time.Since(t0) < 20*time.Millisecond {
+		}
+		return b, time.Since(t0), nil
+	}
+	br, err := runRoundTrip("test", []byte("test"), spin, spin, NewRunOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// allow for the clock tick granularity of CPU accounting
+	if br.CompressCPUTime < 10*time.Millisecond || br.DecompressCPUTime < 10*time.Millisecond {
+		t.Errorf("expected at least 10ms of CPU time but got %s and %s", br.CompressCPUTime, br.DecompressCPUTime)
+	}
+}
+
+func TestCPUUtilization(t *testing.T) {
+	br := BenchmarkResult{
+		CompressTime:      100,
+		DecompressTime:    100,
+		CompressCPUTime:   350,
+		DecompressCPUTime: 50,
+	}
+	if out := br.GetCPUUtilization(); out != 2 {
+		t.Errorf("expected 2 cores but got %v", out)
+	}
+	if out := (&BenchmarkResult{}).GetCPUUtilization(); out != 0 {
+		t.Errorf("expected 0 cores without any time but got %v", out)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// codecs run when the user does not select any with --codecs
+	defaultCodecs = "gzip,zlib,zlib:9,zlib:1,zstd"
+)
+
+var (
+	codecRegistry = map[string]*Codec{}
+)
+
+// Codec describes a compression library which can be selected for benchmarking.
+// DefaultLevel is always supported; Levels lists every other supported level and is
+// the set of levels run by a level sweep
+type Codec struct {
+	Name         string
+	DefaultLevel int
+	Levels       []int
+	Options      []string
+	New          func(level int, opts map[string]string) (Benchmarker, error)
+}
+
+// CodecSpec is a single user selection of a codec, in the form name[:level][:option=value...]
+type CodecSpec struct {
+	Name     string
+	Level    int
+	HasLevel bool
+	Options  map[string]string
+}
+
+// RegisterCodec makes a codec available for selection by name; it panics if the name is already taken
+func RegisterCodec(codec *Codec) {
+	if _, ok := codecRegistry[codec.Name]; ok {
+		panic(fmt.Sprintf("codec '%s' is already registered", codec.Name))
+	}
+	codecRegistry[codec.Name] = codec
+}
+
+// returns the names of all registered codecs in alphabetical order
+func RegisteredCodecNames() []string {
+	names := make([]string, 0, len(codecRegistry))
+	for name := range codecRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parses a comma separated list of codec specs, e.g. "gzip,zstd:3,zlib:9"
+func parseCodecSpecs(s string) ([]CodecSpec, error) {
+	specs := []CodecSpec{}
+	for _, specStr := range strings.Split(s, ",") {
+		specStr = strings.TrimSpace(specStr)
+		if specStr == "" {
+			continue
+		}
+		spec, err := parseCodecSpec(specStr)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no codecs selected")
+	}
+	return specs, nil
+}
+
+func parseCodecSpec(s string) (CodecSpec, error) {
+	parts := strings.Split(s, ":")
+	spec := CodecSpec{
+		Name:    strings.ToLower(parts[0]),
+		Options: map[string]string{},
+	}
+	if spec.Name == "" {
+		return CodecSpec{}, fmt.Errorf("invalid codec '%s': missing name", s)
+	}
+	for _, part := range parts[1:] {
+		if key, value, ok := strings.Cut(part, "="); ok {
+			if key == "" {
+				return CodecSpec{}, fmt.Errorf("invalid codec '%s': empty option name", s)
+			}
+			spec.Options[key] = value
+			continue
+		}
+		if spec.HasLevel {
+			return CodecSpec{}, fmt.Errorf("invalid codec '%s': level given more than once", s)
+		}
+		level, err := strconv.Atoi(part)
+		if err != nil {
+			return CodecSpec{}, fmt.Errorf("invalid codec '%s': level '%s' is not an integer", s, part)
+		}
+		spec.Level = level
+		spec.HasLevel = true
+	}
+	return spec, nil
+}
+
+// replaces the specs with one spec per supported level of each selected codec, keeping
+// the options of the first spec given for each codec
+func expandLevelSweep(specs []CodecSpec) ([]CodecSpec, error) {
+	seen := map[string]bool{}
+	out := []CodecSpec{}
+	for _, spec := range specs {
+		if seen[spec.Name] {
+			continue
+		}
+		seen[spec.Name] = true
+		codec, ok := codecRegistry[spec.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown codec '%s', must be one of: %s", spec.Name, strings.Join(RegisteredCodecNames(), ", "))
+		}
+		for _, level := range codec.Levels {
+			out = append(out, CodecSpec{
+				Name:     spec.Name,
+				Level:    level,
+				HasLevel: true,
+				Options:  spec.Options,
+			})
+		}
+	}
+	return out, nil
+}
+
+// constructs a Benchmarker for each spec, validating levels and options against the registry
+func newBenchmarkers(specs []CodecSpec) ([]Benchmarker, error) {
+	benchmarkers := make([]Benchmarker, 0, len(specs))
+	for _, spec := range specs {
+		codec, ok := codecRegistry[spec.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown codec '%s', must be one of: %s", spec.Name, strings.Join(RegisteredCodecNames(), ", "))
+		}
+		level := codec.DefaultLevel
+		if spec.HasLevel {
+			level = spec.Level
+		}
+		if level != codec.DefaultLevel && !slices.Contains(codec.Levels, level) {
+			return nil, fmt.Errorf("codec '%s' does not support level %d", codec.Name, level)
+		}
+		for key := range spec.Options {
+			if !slices.Contains(codec.Options, key) {
+				return nil, fmt.Errorf("codec '%s' does not support option '%s'", codec.Name, key)
+			}
+		}
+		benchmarker, err := codec.New(level, spec.Options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create codec '%s': %v", codec.Name, err)
+		}
+		benchmarkers = append(benchmarkers, benchmarker)
+	}
+	return benchmarkers, nil
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestParseCodecSpecs(t *testing.T) {
+	type testData struct {
+		name    string
+		input   string
+		exp     []CodecSpec
+		wantErr bool
+	}
+	tests := []testData{
+		{
+			name:  "single codec",
+			input: "gzip",
+			exp: []CodecSpec{
+				{Name: "gzip", Options: map[string]string{}},
+			},
+		},
+		{
+			name:  "codecs with levels",
+			input: "gzip,zstd:3,zlib:9",
+			exp: []CodecSpec{
+				{Name: "gzip", Options: map[string]string{}},
+				{Name: "zstd", Level: 3, HasLevel: true, Options: map[string]string{}},
+				{Name: "zlib", Level: 9, HasLevel: true, Options: map[string]string{}},
+			},
+		},
+		{
+			name:  "negative level",
+			input: "zlib:-1",
+			exp: []CodecSpec{
+				{Name: "zlib", Level: -1, HasLevel: true, Options: map[string]string{}},
+			},
+		},
+		{
+			name:  "options",
+			input: "zstd:2:foo=bar:baz=1",
+			exp: []CodecSpec{
+				{Name: "zstd", Level: 2, HasLevel: true, Options: map[string]string{"foo": "bar", "baz": "1"}},
+			},
+		},
+		{
+			name:  "whitespace and case",
+			input: " GZIP , zlib:1 ",
+			exp: []CodecSpec{
+				{Name: "gzip", Options: map[string]string{}},
+				{Name: "zlib", Level: 1, HasLevel: true, Options: map[string]string{}},
+			},
+		},
+		{
+			name:    "empty",
+			input:   ",",
+			wantErr: true,
+		},
+		{
+			name:    "missing name",
+			input:   ":3",
+			wantErr: true,
+		},
+		{
+			name:    "non-integer level",
+			input:   "zlib:best",
+			wantErr: true,
+		},
+		{
+			name:    "level given twice",
+			input:   "zlib:1:2",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := parseCodecSpecs(test.input)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected error, but did not get one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(out, test.exp) {
+				t.Errorf("expected %v but got %v", test.exp, out)
+			}
+		})
+	}
+}
+
+func TestNewBenchmarkers(t *testing.T) {
+	type testData struct {
+		name     string
+		input    string
+		expNames []string
+		wantErr  bool
+	}
+	tests := []testData{
+		{
+			name:     "default codecs",
+			input:    defaultCodecs,
+			expNames: []string{"std-gzip-6", "std-zlib-6", "std-zlib-9", "std-zlib-1", "zstd-2"},
+		},
+		{
+			name:     "levels",
+			input:    "gzip:9,zlib:-2,zstd:1,zstd:4",
+			expNames: []string{"std-gzip-9", "std-zlib-huffman-only", "zstd-1", "zstd-4"},
+		},
+		{
+			name:     "s2 and snappy",
+			input:    "s2,s2:2,s2:3,snappy",
+			expNames: []string{"s2-1", "s2-2", "s2-3", "snappy"},
+		},
+		{
+			name:     "deflate variants",
+			input:    "gzip:6,kp-gzip:6,kp-gzip,zlib,kp-zlib,flate:1,kp-flate:-2",
+			expNames: []string{"std-gzip-6", "kp-gzip-6", "kp-gzip-5", "std-zlib-6", "kp-zlib-5", "std-flate-1", "kp-flate-huffman-only"},
+		},
+		{
+			name:  "zstd options",
+			input: "zstd:mode=all,zstd:1:concurrency=2:window=1MiB:crc=false,zstd:decoder-concurrency=0:decoder-max-memory=64MB:mode=stream",
+			expNames: []string{
+				"zstd-2:mode=all",
+				"zstd-1:concurrency=2:crc=false:window=1MiB",
+				"zstd-2:decoder-concurrency=0:decoder-max-memory=64MB:mode=stream",
+			},
+		},
+		{
+			name:    "invalid zstd window",
+			input:   "zstd:window=1000",
+			wantErr: true,
+		},
+		{
+			name:    "invalid zstd mode",
+			input:   "zstd:mode=block",
+			wantErr: true,
+		},
+		{
+			name:    "invalid zstd concurrency",
+			input:   "zstd:concurrency=0",
+			wantErr: true,
+		},
+		{
+			name:    "unknown codec",
+			input:   "foo",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported level",
+			input:   "zlib:11",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported option",
+			input:   "gzip:foo=bar",
+			wantErr: true,
+		},
+	}
+	input := []byte("the quick brown fox jumps over the lazy dog")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			specs, err := parseCodecSpecs(test.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			benchmarkers, err := newBenchmarkers(specs)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected error, but did not get one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			names := make([]string, 0, len(benchmarkers))
+			for _, benchmarker := range benchmarkers {
+				result, err := benchmarker.RunBenchmark(input, NewRunOptions())
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				names = append(names, result.Name)
+			}
+			if !reflect.DeepEqual(names, test.expNames) {
+				t.Errorf("expected %v but got %v", test.expNames, names)
+			}
+		})
+	}
+}
+
+func TestExpandLevelSweep(t *testing.T) {
+	specs, err := parseCodecSpecs("zstd:1,gzip,zstd:3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, err := expandLevelSweep(specs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp := len(codecRegistry["zstd"].Levels) + len(codecRegistry["gzip"].Levels)
+	if len(out) != exp {
+		t.Fatalf("expected %d specs but got %d", exp, len(out))
+	}
+	for i, spec := range out {
+		if !spec.HasLevel {
+			t.Errorf("spec %d has no level", i)
+		}
+		if i < len(codecRegistry["zstd"].Levels) && spec.Name != "zstd" {
+			t.Errorf("expected spec %d to be zstd but got %s", i, spec.Name)
+		}
+	}
+	if _, err := expandLevelSweep([]CodecSpec{{Name: "foo"}}); err == nil {
+		t.Errorf("expected error, but did not get one")
+	}
+}
+
+func TestZstdDecoderOptions(t *testing.T) {
+	tests := map[string]int{
+		"":                              0,
+		"decoder-concurrency=0":         1,
+		"decoder-concurrency=2":         1,
+		"decoder-max-memory=64MB:crc=1": 1,
+	}
+	for options, expCount := range tests {
+		opts := map[string]string{}
+		for _, option := range strings.Split(options, ":") {
+			if key, value, ok := strings.Cut(option, "="); ok {
+				opts[key] = value
+			}
+		}
+		zopts, err := parseZstdOptions(opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// a decoder concurrency of 0 is passed on to the library, which then uses GOMAXPROCS
+		if count := len(zstdDecoderOptions(nil, zopts)); count != expCount {
+			t.Errorf("expected %d decoder options for %q but got %d", expCount, options, count)
+		}
+	}
+}
+
+func TestZstdDictSummary(t *testing.T) {
+	opts, err := parseZstdOptions(map[string]string{"concurrency": "2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dict := []byte("dictionary")
+	results := []*BenchmarkResult{
+		{Name: zstdRunnerName(zstd.SpeedDefault, nil, opts), Ratio: 0.5},
+		{Name: zstdRunnerName(zstd.SpeedDefault, dict, opts), Ratio: 0.25},
+	}
+	var out bytes.Buffer
+	printZstdDictSummary(&out, &ZstdDict{Data: dict}, results)
+	exp := "zstd-2:concurrency=2: ratio 50.00% without dictionary, 25.00% with dictionary (50.00% smaller)"
+	if !strings.Contains(out.String(), exp) {
+		t.Errorf("expected the summary to contain %q but got %q", exp, out.String())
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"os"
@@ -10,11 +11,44 @@ import (
 type JsonElement struct {
 	Children []*JsonElement    `json:"children,omitempty"`
 	Fields   map[string]string `json:"fields,omitempty"`
+	// typed values by key, including nested elements. If set, the element is marshalled as a
+	// plain JSON object of these values rather than as fields and children
+	Values map[string]any `json:"-"`
+	// a document of any shape. If set, the element is marshalled as this value
+	Value any `json:"-"`
 }
 
+func (elem *JsonElement) MarshalJSON() ([]byte, error) {
+	if elem.Value != nil {
+		return json.Marshal(elem.Value)
+	}
+	if elem.Values != nil {
+		return json.Marshal(elem.Values)
+	}
+	// the alias has no MarshalJSON method, so it is marshalled by its struct tags
+	type fieldsElement JsonElement
+	return json.Marshal((*fieldsElement)(elem))
+}
+
+// sizes of the JSON syntax around the contents of a marshalled element, used to estimate the size
+// of a document as it is generated
+var (
+	elementOverhead  = len(`{},`)
+	fieldsOverhead   = len(`"fields":{},`)
+	childrenOverhead = len(`"children":[]`)
+	// around a key and its string value
+	fieldOverhead = len(`"":"",`)
+	// around a key and its typed value
+	valueOverhead = len(`"":,`)
+)
+
 // JsonGen implements the JsonGenerator interface
 type JsonGen struct {
 	config *JsonGenConfig
+	// seeded from the config, so that the same config always generates the same sequence of documents
+	rand *rand.Rand
+	// approximate size of the document being generated when marshalled
+	size int
 }
 
 type JsonGenerator interface {
@@ -27,8 +61,18 @@ func NewJsonGenConfig() *JsonGenConfig {
 }
 
 func NewJsonGenerator(config *JsonGenConfig) JsonGenerator {
+	if config.Schema != nil {
+		return &SchemaJsonGen{
+			config: config,
+			rand:   rand.New(rand.NewSource(config.Seed)),
+		}
+	}
+	if config.StableKeys {
+		return newRecordJsonGen(config, rand.New(rand.NewSource(config.Seed)))
+	}
 	return &JsonGen{
 		config: config,
+		rand:   rand.New(rand.NewSource(config.Seed)),
 	}
 }
 
@@ -43,24 +87,39 @@ type JsonGenConfig struct {
 	StrLenMin        int
 	StrLenMax        int
 	NetworkSpeed     uint64
+	// seed of the random source, the same seed and parameters always generate the same documents
+	Seed int64
+	// if set, fields hold values of these types picked by weight, and children are nested objects
+	ValueTypes  []JsonValueWeight
+	ArrayLenMin int
+	ArrayLenMax int
+	// if set, documents conform to this schema instead of being a tree of fields and children
+	Schema *JsonSchema
+	// if set, every document has the keys and structure of the first, with new values
+	StableKeys bool
+	// if set, the root of each document gets more children until the document is about this many
+	// bytes, and no node gets more children once it is
+	TargetSize int
+	// if set, generating a document larger than this many bytes fails
+	MaxSize int
 }
 
-func (conf *JsonGenConfig) numFieldsGetter() func() int {
+func (conf *JsonGenConfig) numFieldsGetter(r *rand.Rand) func() int {
 	return func() int {
-		return GetRandRange(conf.FieldsPerNodeMin, conf.FieldsPerNodeMax)
+		return GetRandRange(r, conf.FieldsPerNodeMin, conf.FieldsPerNodeMax)
 	}
 }
 
-func (conf *JsonGenConfig) numChildrenGetter() func(int) int {
+func (conf *JsonGenConfig) numChildrenGetter(r *rand.Rand) func(int) int {
 	return func(depth int) int {
 		if depth >= conf.DepthMax {
 			return 0
 		}
-		return GetRandRange(conf.DegreeMin, conf.DegreeMax)
+		return GetRandRange(r, conf.DegreeMin, conf.DegreeMax)
 	}
 }
 
-func (conf *JsonGenConfig) strGetter() (func() string, error) {
+func (conf *JsonGenConfig) strGetter(r *rand.Rand) (func() string, error) {
 	if conf.DictFile != "" {
 		// user specified dictionary file
 		file, err := os.Open(conf.DictFile)
@@ -77,7 +136,7 @@ func (conf *JsonGenConfig) strGetter() (func() string, error) {
 			return nil, fmt.Errorf("error reading dictionary file: %v", err)
 		}
 		return func() string {
-			i := GetRandRange(0, len(dict))
+			i := GetRandRange(r, 0, len(dict))
 			return dict[i]
 		}, nil
 	}
@@ -85,48 +144,102 @@ func (conf *JsonGenConfig) strGetter() (func() string, error) {
 		// user wants randomly generated dictionary
 		dict := make([]string, conf.DictSize)
 		for i := range dict {
-			n := GetRandRange(conf.StrLenMin, conf.StrLenMax)
-			dict[i] = RandNChars(n)
+			n := GetRandRange(r, conf.StrLenMin, conf.StrLenMax)
+			dict[i] = RandNChars(r, n)
 		}
 		return func() string {
-			i := GetRandRange(0, len(dict))
+			i := GetRandRange(r, 0, len(dict))
 			return dict[i]
 		}, nil
 	}
 	// user wants randomly generated strings
 	return func() string {
-		n := GetRandRange(conf.StrLenMin, conf.StrLenMax)
-		return RandNChars(n)
+		n := GetRandRange(r, conf.StrLenMin, conf.StrLenMax)
+		return RandNChars(r, n)
 	}, nil
 }
 
 func (gen *JsonGen) JsonGenerate() (*JsonElement, error) {
-	strGetter, err := gen.config.strGetter()
+	strGetter, err := gen.config.strGetter(gen.rand)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate json: %v", err)
 	}
-	return gen.jsonGenerateRec(strGetter, 0), nil
+	gen.size = 0
+	var out *JsonElement
+	if len(gen.config.ValueTypes) > 0 {
+		out, err = gen.jsonGenerateTypedRec(strGetter, gen.config.valueGetter(gen.rand, strGetter), 0)
+	} else {
+		out, err = gen.jsonGenerateRec(strGetter, 0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate json: %v", err)
+	}
+	return out, nil
 }
 
-func (gen *JsonGen) jsonGenerateRec(strGetter func() string, depth int) *JsonElement {
-	numChildren := gen.config.numChildrenGetter()
-	numFields := gen.config.numFieldsGetter()
+func (gen *JsonGen) jsonGenerateRec(strGetter func() string, depth int) (*JsonElement, error) {
+	numChildren := gen.config.numChildrenGetter(gen.rand)
+	numFields := gen.config.numFieldsGetter(gen.rand)
 	out := JsonElement{}
+	if err := gen.grow(elementOverhead); err != nil {
+		return nil, err
+	}
 	nf := numFields()
-	if nf >= 1 {
-		out.Fields = make(map[string]string)
-		for range nf {
-			out.Fields[strGetter()] = strGetter()
+	for i := 0; i < nf || gen.growFields(depth); i++ {
+		if out.Fields == nil {
+			out.Fields = make(map[string]string)
+			if err := gen.grow(fieldsOverhead); err != nil {
+				return nil, err
+			}
+		}
+		key, value := strGetter(), strGetter()
+		out.Fields[key] = value
+		if err := gen.grow(len(key) + len(value) + fieldOverhead); err != nil {
+			return nil, err
 		}
 	}
 	nc := numChildren(depth)
-	if nc >= 1 {
-		out.Children = make([]*JsonElement, 0, nc)
-		for range nc {
-			out.Children = append(out.Children, gen.jsonGenerateRec(strGetter, depth+1))
+	for i := 0; i < nc || gen.growChildren(depth); i++ {
+		if gen.reachedTarget() {
+			break
+		}
+		if out.Children == nil {
+			if err := gen.grow(childrenOverhead); err != nil {
+				return nil, err
+			}
+		}
+		child, err := gen.jsonGenerateRec(strGetter, depth+1)
+		if err != nil {
+			return nil, err
 		}
+		out.Children = append(out.Children, child)
+	}
+	return &out, nil
+}
+
+// adds to the size of the document being generated, failing once it is larger than the maximum size
+func (gen *JsonGen) grow(n int) error {
+	gen.size += n
+	if gen.config.MaxSize > 0 && gen.size > gen.config.MaxSize {
+		return fmt.Errorf("the document is larger than the maximum size of %s", formatBytes(gen.config.MaxSize))
 	}
-	return &out
+	return nil
+}
+
+// returns whether the document being generated has reached its target size, if it has one
+func (gen *JsonGen) reachedTarget() bool {
+	return gen.config.TargetSize > 0 && gen.size >= gen.config.TargetSize
+}
+
+// returns whether the root should get another child to reach the target size
+func (gen *JsonGen) growChildren(depth int) bool {
+	return depth == 0 && depth < gen.config.DepthMax && gen.config.TargetSize > 0 && !gen.reachedTarget()
+}
+
+// returns whether the root should get another field to reach the target size, which is only
+// the case when it cannot have children
+func (gen *JsonGen) growFields(depth int) bool {
+	return depth == 0 && depth >= gen.config.DepthMax && gen.config.TargetSize > 0 && !gen.reachedTarget()
 }
 
 func NumStatic(n int) func(int) int {
@@ -140,41 +253,41 @@ func NumByMaxDepth(max int) func(int) int {
 	}
 }
 
-func RandNChars(n int) string {
+func RandNChars(r *rand.Rand, n int) string {
 	bytes := make([]byte, n)
 	for i := range bytes {
-		bytes[i] = GetRandLowercase()
+		bytes[i] = GetRandLowercase(r)
 	}
 	return string(bytes)
 }
 
-func GetRandLowercase() byte {
-	return byte(GetRandRange(97, 122))
+func GetRandLowercase(r *rand.Rand) byte {
+	return byte(GetRandRange(r, 97, 122))
 }
 
-func GetRandRange(min, max int) int {
+func GetRandRange(r *rand.Rand, min, max int) int {
 	if min == max {
 		return min
 	}
-	return rand.Intn(max-min) + min
+	return r.Intn(max-min) + min
 }
 
-func GenDictionary(wordSize, dictSize int) []string {
+func GenDictionary(r *rand.Rand, wordSize, dictSize int) []string {
 	s := make([]string, 0, dictSize)
 	for _ = range dictSize {
-		s = append(s, RandNChars(wordSize))
+		s = append(s, RandNChars(r, wordSize))
 	}
 	return s
 }
 
-func GetRandFromDictionary(dict []string) func(int) string {
+func GetRandFromDictionary(r *rand.Rand, dict []string) func(int) string {
 	return func(_ int) string {
-		return dict[rand.Intn(len(dict)-1)]
+		return dict[r.Intn(len(dict)-1)]
 	}
 }
 
-func GetRandFromDictionaryAny(dict []string) func(int) any {
+func GetRandFromDictionaryAny(r *rand.Rand, dict []string) func(int) any {
 	return func(_ int) any {
-		return dict[rand.Intn(len(dict)-1)]
+		return dict[r.Intn(len(dict)-1)]
 	}
 }
@@ -1,6 +1,13 @@
 package main
 
-import "testing"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
 
 type JsonConstraint func(t *testing.T, depth int, elem *JsonElement)
 
@@ -109,3 +116,273 @@ func TestJsonGenerate(t *testing.T) {
 		})
 	}
 }
+
+func TestJsonGenerateSeed(t *testing.T) {
+	config := func(seed int64) *JsonGenConfig {
+		return &JsonGenConfig{
+			FieldsPerNodeMin: 1,
+			FieldsPerNodeMax: 5,
+			DegreeMin:        1,
+			DegreeMax:        3,
+			DepthMax:         3,
+			StrLenMin:        4,
+			StrLenMax:        12,
+			DictSize:         50,
+			Seed:             seed,
+		}
+	}
+	generate := func(gen JsonGenerator) string {
+		elem, err := gen.JsonGenerate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out, err := json.Marshal(elem)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return string(out)
+	}
+	gen1 := NewJsonGenerator(config(42))
+	gen2 := NewJsonGenerator(config(42))
+	// the same seed generates the same sequence of documents, while later documents differ
+	first := generate(gen1)
+	if first != generate(gen2) {
+		t.Errorf("expected generators with the same seed to generate the same document")
+	}
+	second := generate(gen1)
+	if second != generate(gen2) {
+		t.Errorf("expected generators with the same seed to generate the same second document")
+	}
+	if first == second {
+		t.Errorf("expected a generator to generate a different document each time")
+	}
+	if first == generate(NewJsonGenerator(config(43))) {
+		t.Errorf("expected generators with different seeds to generate different documents")
+	}
+}
+
+func TestJsonGenerateTyped(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	checks := map[string]func(v any) bool{
+		jsonTypeString: func(v any) bool {
+			s, ok := v.(string)
+			return ok && len(s) == 8
+		},
+		jsonTypeInt: func(v any) bool {
+			_, ok := v.(int)
+			return ok
+		},
+		jsonTypeFloat: func(v any) bool {
+			_, ok := v.(float64)
+			return ok
+		},
+		jsonTypeBool: func(v any) bool {
+			_, ok := v.(bool)
+			return ok
+		},
+		jsonTypeNull: func(v any) bool {
+			return v == nil
+		},
+		jsonTypeArray: func(v any) bool {
+			a, ok := v.([]any)
+			return ok && len(a) >= 2 && len(a) <= 3
+		},
+		jsonTypeTimestamp: func(v any) bool {
+			s, ok := v.(string)
+			if !ok {
+				return false
+			}
+			_, err := time.Parse(time.RFC3339, s)
+			return err == nil
+		},
+		jsonTypeUUID: func(v any) bool {
+			s, ok := v.(string)
+			return ok && uuidPattern.MatchString(s)
+		},
+		jsonTypeBase64: func(v any) bool {
+			s, ok := v.(string)
+			if !ok {
+				return false
+			}
+			blob, err := base64.StdEncoding.DecodeString(s)
+			return err == nil && len(blob) == 8
+		},
+	}
+	for _, valueType := range jsonValueTypes {
+		t.Run(valueType, func(t *testing.T) {
+			gen := NewJsonGenerator(&JsonGenConfig{
+				FieldsPerNodeMin: 5,
+				FieldsPerNodeMax: 5,
+				DegreeMin:        2,
+				DegreeMax:        2,
+				DepthMax:         2,
+				StrLenMin:        8,
+				StrLenMax:        8,
+				ArrayLenMin:      2,
+				ArrayLenMax:      4,
+				ValueTypes:       []JsonValueWeight{{Type: valueType, Weight: 1}, {Type: jsonTypeBool, Weight: 0}},
+			})
+			elem, err := gen.JsonGenerate()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// children are nested objects, and every other value is of the only type with a weight
+			stack := []*JsonElement{elem}
+			objects := 0
+			for len(stack) > 0 {
+				cur := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				objects++
+				for key, value := range cur.Values {
+					if child, ok := value.(*JsonElement); ok {
+						stack = append(stack, child)
+					} else if !checks[valueType](value) {
+						t.Errorf("value %v of %s is not a valid %s", value, key, valueType)
+					}
+				}
+			}
+			if objects != 7 {
+				t.Errorf("expected 7 nested objects, got %d", objects)
+			}
+			out, err := json.Marshal(elem)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Contains(string(out), `"fields"`) || strings.Contains(string(out), `"children"`) {
+				t.Errorf("expected a plain JSON object, got %s", out)
+			}
+		})
+	}
+}
+
+func TestJsonGenerateTypedDict(t *testing.T) {
+	// with a dictionary barely larger than an object, keys are often drawn twice
+	gen := NewJsonGenerator(&JsonGenConfig{
+		FieldsPerNodeMin: 5,
+		FieldsPerNodeMax: 5,
+		DegreeMin:        2,
+		DegreeMax:        2,
+		DepthMax:         2,
+		StrLenMin:        8,
+		StrLenMax:        8,
+		DictSize:         8,
+		ValueTypes:       []JsonValueWeight{{Type: jsonTypeArray, Weight: 1}},
+	})
+	elem, err := gen.JsonGenerate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	type node struct {
+		elem  *JsonElement
+		depth int
+	}
+	stack := []node{{elem, 0}}
+	maxArrayLen := 0
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		children := 0
+		for _, value := range cur.elem.Values {
+			switch v := value.(type) {
+			case *JsonElement:
+				stack = append(stack, node{v, cur.depth + 1})
+				children++
+			case []any:
+				if len(v) < defaultArrayLenMin {
+					t.Errorf("array of %d elements is shorter than the default length", len(v))
+				}
+				maxArrayLen = max(maxArrayLen, len(v))
+			}
+		}
+		// no field or child was overwritten by another under the same key
+		expChildren := 2
+		if cur.depth == 2 {
+			expChildren = 0
+		}
+		if len(cur.elem.Values) != 5+expChildren || children != expChildren {
+			t.Errorf("expected 5 fields and %d children, got %d values of which %d children", expChildren, len(cur.elem.Values), children)
+		}
+	}
+	// arrays have between 1 and 6 elements by default
+	if maxArrayLen != 6 {
+		t.Errorf("expected the longest array to have 6 elements, got %d", maxArrayLen)
+	}
+}
+
+func TestJsonGenerateTargetSize(t *testing.T) {
+	tests := []struct {
+		name   string
+		config JsonGenConfig
+	}{
+		{
+			name:   "fields and children",
+			config: JsonGenConfig{FieldsPerNodeMin: 3, FieldsPerNodeMax: 3, DegreeMin: 4, DegreeMax: 4, DepthMax: 5, StrLenMin: 16, StrLenMax: 16},
+		},
+		{
+			name:   "dictionary and ranges",
+			config: JsonGenConfig{FieldsPerNodeMin: 0, FieldsPerNodeMax: 6, DegreeMin: 0, DegreeMax: 3, DepthMax: 8, StrLenMin: 2, StrLenMax: 30, DictSize: 1000},
+		},
+		{
+			name:   "no children",
+			config: JsonGenConfig{FieldsPerNodeMin: 3, FieldsPerNodeMax: 3, DepthMax: 0, StrLenMin: 16, StrLenMax: 16},
+		},
+		{
+			name: "typed values",
+			config: JsonGenConfig{FieldsPerNodeMin: 5, FieldsPerNodeMax: 5, DegreeMin: 2, DegreeMax: 2, DepthMax: 3, StrLenMin: 8, StrLenMax: 8,
+				ValueTypes: []JsonValueWeight{{Type: jsonTypeString, Weight: 1}, {Type: jsonTypeFloat, Weight: 1}, {Type: jsonTypeArray, Weight: 1}, {Type: jsonTypeUUID, Weight: 1}}},
+		},
+	}
+	for _, test := range tests {
+		for _, target := range []int{1000, 100000, 2000000} {
+			config := test.config
+			config.TargetSize = target
+			elem, err := NewJsonGenerator(&config).JsonGenerate()
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", test.name, err)
+			}
+			out, err := json.Marshal(elem)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", test.name, err)
+			}
+			// the last node added may take the document past its target, which matters most for small targets
+			if size := len(out); size < target*9/10 || size > target*11/10+500 {
+				t.Errorf("%s: generated %d bytes, expected about %d", test.name, size, target)
+			}
+		}
+	}
+}
+
+func TestJsonGenerateMaxSize(t *testing.T) {
+	config := &JsonGenConfig{
+		FieldsPerNodeMin: 3,
+		FieldsPerNodeMax: 3,
+		DegreeMin:        10,
+		DegreeMax:        10,
+		DepthMax:         12,
+		StrLenMin:        16,
+		StrLenMax:        16,
+		MaxSize:          1000000,
+	}
+	// the tree would be far too large to fit in memory without the maximum size
+	if _, err := NewJsonGenerator(config).JsonGenerate(); err == nil || !strings.Contains(err.Error(), "maximum size") {
+		t.Errorf("expected an error for a document larger than the maximum size, got %v", err)
+	}
+	config.DepthMax = 2
+	if _, err := NewJsonGenerator(config).JsonGenerate(); err != nil {
+		t.Errorf("unexpected error for a document smaller than the maximum size: %v", err)
+	}
+}
+
+func TestJsonElementMarshal(t *testing.T) {
+	elem := &JsonElement{
+		Fields:   map[string]string{"a": "b"},
+		Children: []*JsonElement{{Fields: map[string]string{"c": "d"}}},
+	}
+	out, err := json.Marshal(elem)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exp := `{"children":[{"fields":{"c":"d"}}],"fields":{"a":"b"}}`; string(out) != exp {
+		t.Errorf("expected %s, got %s", exp, out)
+	}
+}
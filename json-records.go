@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"slices"
+)
+
+const (
+	// formats of a batch of records
+	recordsNdjson = "ndjson"
+	recordsArray  = "array"
+)
+
+var recordFormats = []string{recordsNdjson, recordsArray}
+
+// RecordOptions control how many documents are generated into a single batch of records
+type RecordOptions struct {
+	// number of records, or zero to generate records up to Size
+	Count int
+	// total size of the batch in bytes, which it does not exceed unless a single record does
+	Size   int
+	Format string
+}
+
+// RecordJsonGen implements the JsonGenerator interface. It generates a template document, then
+// every document it returns has the keys and structure of the template with new values
+type RecordJsonGen struct {
+	config *JsonGenConfig
+	rand   *rand.Rand
+	// generates the template, then the values of each record
+	template  JsonGenerator
+	values    *SchemaJsonGen
+	shape     any
+	strGetter func() string
+}
+
+func newRecordJsonGen(config *JsonGenConfig, r *rand.Rand) *RecordJsonGen {
+	return &RecordJsonGen{
+		config:   config,
+		rand:     r,
+		template: &JsonGen{config: config, rand: r},
+		values:   &SchemaJsonGen{config: config, rand: r},
+	}
+}
+
+func (gen *RecordJsonGen) JsonGenerate() (*JsonElement, error) {
+	if gen.shape == nil {
+		template, err := gen.template.JsonGenerate()
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate json: %v", err)
+		}
+		if gen.shape, err = decodeJsonValue(data); err != nil {
+			return nil, fmt.Errorf("failed to generate json: %v", err)
+		}
+		// the strings of every record come from the same dictionary, as they would in a real stream
+		if gen.strGetter, err = gen.config.strGetter(gen.rand); err != nil {
+			return nil, fmt.Errorf("failed to generate json: %v", err)
+		}
+	}
+	record, err := gen.vary(gen.shape)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate json: %v", err)
+	}
+	return &JsonElement{Value: record}, nil
+}
+
+// returns a value of the same shape as the template value. Objects keep their keys and arrays
+// their length, formatted strings keep their format, other strings are generated as configured,
+// and numbers range from zero to twice the template's value
+func (gen *RecordJsonGen) vary(value any) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		// keys are visited in a fixed order, so that the same seed generates the same records
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		record := make(map[string]any, len(v))
+		for _, key := range keys {
+			elem, err := gen.vary(v[key])
+			if err != nil {
+				return nil, err
+			}
+			record[key] = elem
+		}
+		return record, nil
+	case []any:
+		array := make([]any, len(v))
+		for i, elem := range v {
+			varied, err := gen.vary(elem)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = varied
+		}
+		return array, nil
+	case string:
+		if inferFormat(v) == "" {
+			return gen.strGetter(), nil
+		}
+	}
+	return gen.values.generate(inferSchema(value), gen.strGetter, 0)
+}
+
+// generates a batch of records, either one document per line or a JSON array of documents. Fails
+// once the batch is larger than maxSize, unless it is zero
+func generateRecords(generator JsonGenerator, opts *RecordOptions, maxSize int) ([]byte, error) {
+	var buf bytes.Buffer
+	separator, end := []byte("\n"), []byte("\n")
+	if opts.Format == recordsArray {
+		buf.WriteByte('[')
+		separator, end = []byte(","), []byte("]")
+	}
+	for n := 0; opts.Count == 0 || n < opts.Count; n++ {
+		randJson, err := generator.JsonGenerate()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate random JSON: %v", err)
+		}
+		record, err := json.Marshal(randJson)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Count == 0 && n > 0 && buf.Len()+len(separator)+len(record)+len(end) > opts.Size {
+			break
+		}
+		if n > 0 {
+			buf.Write(separator)
+		}
+		buf.Write(record)
+		if maxSize > 0 && buf.Len()+len(end) > maxSize {
+			return nil, fmt.Errorf("failed to generate random JSON: the records are larger than the maximum size of %s", formatBytes(maxSize))
+		}
+	}
+	buf.Write(end)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRecordConfig() *JsonGenConfig {
+	config := NewJsonGenConfig()
+	config.FieldsPerNodeMin, config.FieldsPerNodeMax = 4, 4
+	config.DegreeMin, config.DegreeMax = 2, 2
+	config.DepthMax = 2
+	config.StrLenMin, config.StrLenMax = 8, 8
+	config.ValueTypes = []JsonValueWeight{
+		{Type: jsonTypeString, Weight: 1},
+		{Type: jsonTypeInt, Weight: 1},
+		{Type: jsonTypeTimestamp, Weight: 1},
+		{Type: jsonTypeArray, Weight: 1},
+	}
+	config.Seed = 11
+	config.StableKeys = true
+	return config
+}
+
+// returns the sorted paths of every key of a document, such as a.b
+func keyPaths(prefix string, value any) []string {
+	var paths []string
+	switch v := value.(type) {
+	case map[string]any:
+		for key, elem := range v {
+			paths = append(paths, prefix+"."+key)
+			paths = append(paths, keyPaths(prefix+"."+key, elem)...)
+		}
+	case []any:
+		for _, elem := range v {
+			paths = append(paths, keyPaths(prefix+"[]", elem)...)
+		}
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func TestRecordJsonGenerate(t *testing.T) {
+	gen := NewJsonGenerator(newTestRecordConfig())
+	var firstKeys []string
+	var firstRecord []byte
+	for i := range 20 {
+		elem, err := gen.JsonGenerate()
+		if err != nil {
+			t.Fatalf("failed to generate json: %v", err)
+		}
+		data, err := json.Marshal(elem)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var record map[string]any
+		if err := json.Unmarshal(data, &record); err != nil {
+			t.Fatalf("generated invalid json %s: %v", data, err)
+		}
+		keys := keyPaths("", record)
+		if i == 0 {
+			firstKeys, firstRecord = keys, data
+			if len(keys) == 0 {
+				t.Fatalf("record %s has no keys", data)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(keys, firstKeys) {
+			t.Errorf("record %d has keys %v, expected the keys of the first record %v", i, keys, firstKeys)
+		}
+		if bytes.Equal(data, firstRecord) {
+			t.Errorf("record %d has the same values as the first record", i)
+		}
+	}
+}
+
+func TestRecordJsonVary(t *testing.T) {
+	gen := NewJsonGenerator(newTestRecordConfig()).(*RecordJsonGen)
+	if _, err := gen.JsonGenerate(); err != nil {
+		t.Fatal(err)
+	}
+	template, err := decodeJsonValue([]byte(`{"n": 50, "f": 1.5, "s": "abc", "t": "2024-01-02T03:04:05Z", "b": "3q2+7w==", "a": [true, null]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	random := false
+	for range 50 {
+		value, err := gen.vary(template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		record := value.(map[string]any)
+		if n, ok := record["n"].(int64); !ok || n < 0 || n > 100 {
+			t.Errorf("n %v is not an integer between 0 and 100", record["n"])
+		}
+		if f, ok := record["f"].(float64); !ok || f < 0 || f > 3 {
+			t.Errorf("f %v is not a number between 0 and 3", record["f"])
+		}
+		if s, ok := record["s"].(string); !ok || len(s) != 8 {
+			t.Errorf("s %v is not a string of the configured length", record["s"])
+		}
+		if _, err := time.Parse(time.RFC3339, record["t"].(string)); err != nil {
+			t.Errorf("t %v is not a timestamp: %v", record["t"], err)
+		}
+		// base64 values are random bytes of the same encoded length, like the base64 values of typed JSON
+		if b := record["b"].(string); len(b) != 8 {
+			t.Errorf("b %v is not of the template's length", b)
+		} else if _, err := base64.StdEncoding.DecodeString(b); err != nil {
+			t.Errorf("b %v is not base64: %v", b, err)
+		}
+		random = random || strings.ContainsFunc(record["b"].(string), func(c rune) bool { return c < 'a' || c > 'z' })
+		array := record["a"].([]any)
+		if _, ok := array[0].(bool); !ok || len(array) != 2 || array[1] != nil {
+			t.Errorf("a %v does not have the shape of the template", array)
+		}
+	}
+	// random strings are lowercase letters, which are valid base64 too, but random bytes are not
+	if !random {
+		t.Error("b was varied as a random string instead of random bytes")
+	}
+}
+
+func TestGenerateRecords(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      RecordOptions
+		expCount  int
+		checkSize bool
+	}{
+		{
+			name:     "ndjson count",
+			opts:     RecordOptions{Count: 25, Format: recordsNdjson},
+			expCount: 25,
+		},
+		{
+			name:     "array count",
+			opts:     RecordOptions{Count: 25, Format: recordsArray},
+			expCount: 25,
+		},
+		{
+			name:      "ndjson size",
+			opts:      RecordOptions{Size: 20000, Format: recordsNdjson},
+			checkSize: true,
+		},
+		{
+			name:      "array size",
+			opts:      RecordOptions{Size: 20000, Format: recordsArray},
+			checkSize: true,
+		},
+		{
+			name:     "size smaller than a record",
+			opts:     RecordOptions{Size: 1, Format: recordsArray},
+			expCount: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			batch, err := generateRecords(NewJsonGenerator(newTestRecordConfig()), &test.opts, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var records []map[string]any
+			if test.opts.Format == recordsArray {
+				if err := json.Unmarshal(batch, &records); err != nil {
+					t.Fatalf("batch is not a JSON array: %v", err)
+				}
+			} else {
+				if !bytes.HasSuffix(batch, []byte("\n")) {
+					t.Errorf("batch does not end with a newline")
+				}
+				for _, line := range splitMessageLines(batch) {
+					var record map[string]any
+					if err := json.Unmarshal(line, &record); err != nil {
+						t.Fatalf("line %s is not a JSON document: %v", line, err)
+					}
+					records = append(records, record)
+				}
+			}
+			if test.expCount > 0 && len(records) != test.expCount {
+				t.Errorf("generated %d records, expected %d", len(records), test.expCount)
+			}
+			if test.checkSize {
+				if len(batch) > test.opts.Size {
+					t.Errorf("batch of %d bytes exceeds the size of %d", len(batch), test.opts.Size)
+				}
+				// another record would not have fit
+				recordSize := len(batch) / len(records)
+				if len(batch) < test.opts.Size-2*recordSize {
+					t.Errorf("batch of %d bytes of %d records is much smaller than the size of %d", len(batch), len(records), test.opts.Size)
+				}
+			}
+		})
+	}
+}
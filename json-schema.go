@@ -0,0 +1,649 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// JSON Schema types
+	schemaTypeObject  = "object"
+	schemaTypeArray   = "array"
+	schemaTypeString  = "string"
+	schemaTypeInteger = "integer"
+	schemaTypeNumber  = "number"
+	schemaTypeBoolean = "boolean"
+	schemaTypeNull    = "null"
+
+	// generated numbers without bounds fall within this range
+	defaultSchemaNumberRange = 1000000
+
+	// how likely a property which is not required is to be generated
+	optionalPropertyChance = 0.5
+
+	// nesting beyond this depth is an error, since only a recursive schema which requires
+	// itself can reach it
+	maxSchemaDepth = 64
+)
+
+var (
+	schemaTypes = []string{
+		schemaTypeObject, schemaTypeArray, schemaTypeString, schemaTypeInteger,
+		schemaTypeNumber, schemaTypeBoolean, schemaTypeNull,
+	}
+
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// JsonSchema is the subset of JSON Schema which documents can be generated from
+type JsonSchema struct {
+	Type       schemaTypeList         `json:"type,omitempty"`
+	Properties map[string]*JsonSchema `json:"properties,omitempty"`
+	Required   []string               `json:"required,omitempty"`
+	Items      *JsonSchema            `json:"items,omitempty"`
+	MinItems   *int                   `json:"minItems,omitempty"`
+	MaxItems   *int                   `json:"maxItems,omitempty"`
+	MinLength  *int                   `json:"minLength,omitempty"`
+	MaxLength  *int                   `json:"maxLength,omitempty"`
+	Format     string                 `json:"format,omitempty"`
+	Enum       []any                  `json:"enum,omitempty"`
+	// raw, so that a const of null can be told apart from no const
+	Const            json.RawMessage `json:"const,omitempty"`
+	Minimum          *float64        `json:"minimum,omitempty"`
+	Maximum          *float64        `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64        `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64        `json:"exclusiveMaximum,omitempty"`
+	OneOf            []*JsonSchema   `json:"oneOf,omitempty"`
+	AnyOf            []*JsonSchema   `json:"anyOf,omitempty"`
+	AllOf            []*JsonSchema   `json:"allOf,omitempty"`
+	// only local references to the schema itself or its definitions are supported
+	Ref         string                 `json:"$ref,omitempty"`
+	Defs        map[string]*JsonSchema `json:"$defs,omitempty"`
+	Definitions map[string]*JsonSchema `json:"definitions,omitempty"`
+}
+
+// schemaTypeList is the type keyword of a schema, which is either a single type or a list of types
+type schemaTypeList []string
+
+func (tl *schemaTypeList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*tl = schemaTypeList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*tl = list
+	return nil
+}
+
+// reads a JSON Schema from a file, checking that every type it names is known
+func readJsonSchema(path string) (*JsonSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	schema := &JsonSchema{}
+	if err := json.Unmarshal(data, schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	if err := checkSchemaTypes(schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	return schema, nil
+}
+
+func checkSchemaTypes(schema *JsonSchema) error {
+	if schema == nil {
+		return nil
+	}
+	for _, schemaType := range schema.Type {
+		if !slices.Contains(schemaTypes, schemaType) {
+			return fmt.Errorf("unknown type '%s'", schemaType)
+		}
+	}
+	children := []*JsonSchema{schema.Items}
+	for _, subschemas := range [][]*JsonSchema{schema.OneOf, schema.AnyOf, schema.AllOf} {
+		children = append(children, subschemas...)
+	}
+	for _, subschemas := range []map[string]*JsonSchema{schema.Properties, schema.Defs, schema.Definitions} {
+		for _, subschema := range subschemas {
+			children = append(children, subschema)
+		}
+	}
+	for _, child := range children {
+		if err := checkSchemaTypes(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reads an example document from a file, and infers a schema which it conforms to
+func readJsonExample(path string) (*JsonSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	example, err := decodeJsonValue(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid example document: %v", err)
+	}
+	return inferSchema(example), nil
+}
+
+// decodes a JSON document, keeping numbers as json.Number so that integers can be told apart
+func decodeJsonValue(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	err := decoder.Decode(&value)
+	return value, err
+}
+
+// returns a schema which the value conforms to. Numbers may be anywhere between zero and twice
+// their value, strings keep their length or format, and arrays keep their length
+func inferSchema(value any) *JsonSchema {
+	switch v := value.(type) {
+	case nil:
+		return &JsonSchema{Type: schemaTypeList{schemaTypeNull}}
+	case bool:
+		return &JsonSchema{Type: schemaTypeList{schemaTypeBoolean}}
+	case json.Number:
+		f, _ := v.Float64()
+		low, high := min(0, 2*f), max(0, 2*f)
+		schemaType := schemaTypeNumber
+		if _, err := v.Int64(); err == nil {
+			schemaType = schemaTypeInteger
+		}
+		return &JsonSchema{Type: schemaTypeList{schemaType}, Minimum: &low, Maximum: &high}
+	case string:
+		schema := &JsonSchema{Type: schemaTypeList{schemaTypeString}, Format: inferFormat(v)}
+		if schema.Format == "" || schema.Format == "byte" {
+			length := len(v)
+			schema.MinLength = &length
+			schema.MaxLength = &length
+		}
+		return schema
+	case []any:
+		length := len(v)
+		schema := &JsonSchema{Type: schemaTypeList{schemaTypeArray}, MinItems: &length, MaxItems: &length}
+		for _, elem := range v {
+			schema.Items = mergeSchemas(schema.Items, inferSchema(elem))
+		}
+		return schema
+	case map[string]any:
+		schema := &JsonSchema{Type: schemaTypeList{schemaTypeObject}, Properties: map[string]*JsonSchema{}}
+		for key, elem := range v {
+			schema.Properties[key] = inferSchema(elem)
+			schema.Required = append(schema.Required, key)
+		}
+		slices.Sort(schema.Required)
+		return schema
+	}
+	return &JsonSchema{}
+}
+
+// returns the format of a string if it looks like one which can be generated
+func inferFormat(s string) string {
+	if _, err := time.Parse(time.RFC3339, s); err == nil {
+		return "date-time"
+	}
+	if _, err := time.Parse(time.DateOnly, s); err == nil {
+		return "date"
+	}
+	if uuidPattern.MatchString(s) {
+		return "uuid"
+	}
+	if name, domain, ok := strings.Cut(s, "@"); ok && name != "" && strings.Contains(domain, ".") && !strings.ContainsAny(s, " \t") {
+		return "email"
+	}
+	if u, err := url.Parse(s); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		return "uri"
+	}
+	if looksLikeBase64(s) {
+		return "byte"
+	}
+	return ""
+}
+
+// returns whether the string looks like base64 encoded bytes rather than text: it must decode, and
+// mix letters with digits, '+', '/' or padding, which random bytes nearly always do but words and
+// numbers do not. Short strings must be padded. Text which happens to look like that, such as
+// "user1234", is taken for base64
+func looksLikeBase64(s string) bool {
+	if len(s) < 8 && !strings.HasSuffix(s, "=") {
+		return false
+	}
+	letters := strings.IndexFunc(s, func(c rune) bool {
+		return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+	}) >= 0
+	others := strings.ContainsAny(s, "0123456789+/=")
+	if !letters || !others {
+		return false
+	}
+	_, err := base64.StdEncoding.DecodeString(s)
+	return err == nil
+}
+
+// combines the schemas inferred from two values into a schema which both conform to
+func mergeSchemas(a, b *JsonSchema) *JsonSchema {
+	if a == nil {
+		return b
+	}
+	merged := *a
+	merged.Type = slices.Clone(a.Type)
+	for _, schemaType := range b.Type {
+		if !slices.Contains(merged.Type, schemaType) {
+			merged.Type = append(merged.Type, schemaType)
+		}
+	}
+	if b.Properties != nil {
+		merged.Properties = map[string]*JsonSchema{}
+		for key, schema := range a.Properties {
+			merged.Properties[key] = schema
+		}
+		for key, schema := range b.Properties {
+			merged.Properties[key] = mergeSchemas(merged.Properties[key], schema)
+		}
+		// only the properties of both values are required
+		if a.Properties != nil {
+			merged.Required = nil
+			for _, key := range a.Required {
+				if slices.Contains(b.Required, key) {
+					merged.Required = append(merged.Required, key)
+				}
+			}
+		} else {
+			merged.Required = b.Required
+		}
+	}
+	if b.Items != nil {
+		merged.Items = mergeSchemas(a.Items, b.Items)
+	}
+	if a.Format != b.Format {
+		merged.Format = ""
+		if a.Format != "" || b.Format != "" {
+			// a formatted string and another string, so fall back to any string of the other's length
+			merged.MinLength, merged.MaxLength = nil, nil
+		}
+	}
+	merged.MinItems = mergeBound(a.MinItems, b.MinItems, true)
+	merged.MaxItems = mergeBound(a.MaxItems, b.MaxItems, false)
+	if merged.Format == "" && a.Format == "" && b.Format == "" {
+		merged.MinLength = mergeBound(a.MinLength, b.MinLength, true)
+		merged.MaxLength = mergeBound(a.MaxLength, b.MaxLength, false)
+	}
+	merged.Minimum = mergeBound(a.Minimum, b.Minimum, true)
+	merged.Maximum = mergeBound(a.Maximum, b.Maximum, false)
+	return &merged
+}
+
+// combines two optional bounds, keeping the lower or higher of the two, or whichever is set if only one is
+func mergeBound[T int | float64](a, b *T, lower bool) *T {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	bound := max(*a, *b)
+	if lower {
+		bound = min(*a, *b)
+	}
+	return &bound
+}
+
+// SchemaJsonGen implements the JsonGenerator interface, generating documents which conform to a schema
+type SchemaJsonGen struct {
+	config *JsonGenConfig
+	rand   *rand.Rand
+}
+
+func (gen *SchemaJsonGen) JsonGenerate() (*JsonElement, error) {
+	strGetter, err := gen.config.strGetter(gen.rand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate json: %v", err)
+	}
+	schema, err := gen.rootSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate json: %v", err)
+	}
+	value, err := gen.generate(schema, strGetter, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate json: %v", err)
+	}
+	if value == nil {
+		return nil, fmt.Errorf("failed to generate json: the schema only allows null")
+	}
+	return &JsonElement{Value: value}, nil
+}
+
+// returns the schema of every generated document. A document is never null, so null is left out
+// of the types the root may have unless it is the only one
+func (gen *SchemaJsonGen) rootSchema() (*JsonSchema, error) {
+	schema, err := gen.resolve(gen.config.Schema)
+	if err != nil {
+		return nil, err
+	}
+	types := slices.DeleteFunc(slices.Clone(schema.Type), func(t string) bool {
+		return t == schemaTypeNull
+	})
+	if len(types) == 0 || len(types) == len(schema.Type) {
+		return schema, nil
+	}
+	root := *schema
+	root.Type = types
+	return &root, nil
+}
+
+// generates a value which conforms to the schema. Beyond the maximum depth of the config, optional
+// properties are left out and arrays are as short as the schema allows
+func (gen *SchemaJsonGen) generate(schema *JsonSchema, strGetter func() string, depth int) (any, error) {
+	if depth > maxSchemaDepth {
+		return nil, fmt.Errorf("the required values of the schema nest deeper than %d levels", maxSchemaDepth)
+	}
+	schema, err := gen.resolve(schema)
+	if err != nil {
+		return nil, err
+	}
+	if schema.Const != nil {
+		var value any
+		return value, json.Unmarshal(schema.Const, &value)
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[gen.rand.Intn(len(schema.Enum))], nil
+	}
+	switch gen.pickType(schema) {
+	case schemaTypeObject:
+		return gen.generateObject(schema, strGetter, depth)
+	case schemaTypeArray:
+		return gen.generateArray(schema, strGetter, depth)
+	case schemaTypeInteger:
+		low, high := gen.numberBounds(schema)
+		// integers are generated as int64, so bounds beyond its range are clamped to it
+		low = math.Ceil(max(low, math.MinInt64))
+		high = math.Floor(min(high, math.Nextafter(math.MaxInt64, 0)))
+		if low > high {
+			return nil, fmt.Errorf("no integer is between the minimum and maximum of the schema")
+		}
+		if span := high - low; span < math.MaxInt64 {
+			return int64(low) + gen.rand.Int63n(int64(span)+1), nil
+		}
+		// the number of integers between the bounds does not fit in an int64
+		return int64(low + gen.rand.Float64()*(high-low)), nil
+	case schemaTypeNumber:
+		low, high := gen.numberBounds(schema)
+		value := low + gen.rand.Float64()*(high-low)
+		// real numbers rarely have many decimal places, unless the bounds need them
+		if rounded := math.Round(value*100) / 100; rounded >= low && rounded <= high {
+			value = rounded
+		}
+		return value, nil
+	case schemaTypeBoolean:
+		return gen.rand.Intn(2) == 1, nil
+	case schemaTypeNull:
+		return nil, nil
+	default:
+		return gen.generateString(schema, strGetter), nil
+	}
+}
+
+// follows references and combines allOf subschemas, returning a schema which can be generated directly
+func (gen *SchemaJsonGen) resolve(schema *JsonSchema) (*JsonSchema, error) {
+	for range maxSchemaDepth {
+		switch {
+		case schema.Ref != "":
+			target, err := gen.lookupRef(schema.Ref)
+			if err != nil {
+				return nil, err
+			}
+			schema = target
+		case len(schema.OneOf) > 0:
+			schema = schema.OneOf[gen.rand.Intn(len(schema.OneOf))]
+		case len(schema.AnyOf) > 0:
+			schema = schema.AnyOf[gen.rand.Intn(len(schema.AnyOf))]
+		case len(schema.AllOf) > 0:
+			combined := *schema
+			combined.AllOf = nil
+			for _, subschema := range schema.AllOf {
+				resolved, err := gen.resolve(subschema)
+				if err != nil {
+					return nil, err
+				}
+				combined = overlaySchema(combined, resolved)
+			}
+			schema = &combined
+		default:
+			return schema, nil
+		}
+	}
+	return nil, fmt.Errorf("the references of the schema nest deeper than %d levels", maxSchemaDepth)
+}
+
+// returns the local definition a reference such as #/$defs/name points to
+func (gen *SchemaJsonGen) lookupRef(ref string) (*JsonSchema, error) {
+	root := gen.config.Schema
+	if ref == "#" {
+		return root, nil
+	}
+	for _, prefix := range []string{"#/$defs/", "#/definitions/"} {
+		name, ok := strings.CutPrefix(ref, prefix)
+		if !ok {
+			continue
+		}
+		// unescape the JSON pointer
+		name = strings.ReplaceAll(strings.ReplaceAll(name, "~1", "/"), "~0", "~")
+		defs := root.Defs
+		if prefix == "#/definitions/" {
+			defs = root.Definitions
+		}
+		if target, ok := defs[name]; ok {
+			return target, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported or missing reference '%s'", ref)
+}
+
+// adds the keywords of extra which base does not have, as allOf requires a value to satisfy both
+func overlaySchema(base JsonSchema, extra *JsonSchema) JsonSchema {
+	if len(base.Type) == 0 {
+		base.Type = extra.Type
+	}
+	if extra.Properties != nil {
+		properties := map[string]*JsonSchema{}
+		for key, schema := range base.Properties {
+			properties[key] = schema
+		}
+		for key, schema := range extra.Properties {
+			if _, ok := properties[key]; !ok {
+				properties[key] = schema
+			}
+		}
+		base.Properties = properties
+	}
+	for _, key := range extra.Required {
+		if !slices.Contains(base.Required, key) {
+			base.Required = append(base.Required, key)
+		}
+	}
+	if base.Items == nil {
+		base.Items = extra.Items
+	}
+	if base.Format == "" {
+		base.Format = extra.Format
+	}
+	if base.Enum == nil {
+		base.Enum = extra.Enum
+	}
+	if base.Const == nil {
+		base.Const = extra.Const
+	}
+	base.MinItems = mergeBound(base.MinItems, extra.MinItems, false)
+	base.MaxItems = mergeBound(base.MaxItems, extra.MaxItems, true)
+	base.MinLength = mergeBound(base.MinLength, extra.MinLength, false)
+	base.MaxLength = mergeBound(base.MaxLength, extra.MaxLength, true)
+	base.Minimum = mergeBound(base.Minimum, extra.Minimum, false)
+	base.Maximum = mergeBound(base.Maximum, extra.Maximum, true)
+	base.ExclusiveMinimum = mergeBound(base.ExclusiveMinimum, extra.ExclusiveMinimum, false)
+	base.ExclusiveMaximum = mergeBound(base.ExclusiveMaximum, extra.ExclusiveMaximum, true)
+	return base
+}
+
+// returns one of the types of the schema, or the type its keywords imply if it has none
+func (gen *SchemaJsonGen) pickType(schema *JsonSchema) string {
+	if len(schema.Type) > 0 {
+		return schema.Type[gen.rand.Intn(len(schema.Type))]
+	}
+	switch {
+	case schema.Properties != nil:
+		return schemaTypeObject
+	case schema.Items != nil || schema.MinItems != nil || schema.MaxItems != nil:
+		return schemaTypeArray
+	case schema.Minimum != nil || schema.Maximum != nil || schema.ExclusiveMinimum != nil || schema.ExclusiveMaximum != nil:
+		return schemaTypeNumber
+	}
+	return schemaTypeString
+}
+
+func (gen *SchemaJsonGen) generateObject(schema *JsonSchema, strGetter func() string, depth int) (any, error) {
+	// properties are generated in a fixed order, so that the same seed generates the same document
+	keys := make([]string, 0, len(schema.Properties))
+	for key := range schema.Properties {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	object := map[string]any{}
+	for _, key := range keys {
+		if !slices.Contains(schema.Required, key) {
+			if depth >= gen.config.DepthMax || gen.rand.Float64() >= optionalPropertyChance {
+				continue
+			}
+		}
+		value, err := gen.generate(schema.Properties[key], strGetter, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		object[key] = value
+	}
+	return object, nil
+}
+
+func (gen *SchemaJsonGen) generateArray(schema *JsonSchema, strGetter func() string, depth int) (any, error) {
+	// without bounds, arrays are as long as configured for typed values
+	defaultMin, defaultMax := gen.config.ArrayLenMin, gen.config.ArrayLenMax-1
+	if gen.config.ArrayLenMax == 0 {
+		defaultMin, defaultMax = defaultArrayLenMin, defaultArrayLenMax-1
+	}
+	low, high := defaultMin, defaultMax
+	if schema.MinItems != nil {
+		low = *schema.MinItems
+		high = max(high, low)
+	}
+	if schema.MaxItems != nil {
+		high = *schema.MaxItems
+		low = min(low, high)
+	}
+	if low > high {
+		return nil, fmt.Errorf("minItems of the schema is greater than maxItems")
+	}
+	n := low
+	if depth < gen.config.DepthMax {
+		n = GetRandRange(gen.rand, low, high+1)
+	}
+	items := schema.Items
+	if items == nil {
+		items = &JsonSchema{Type: schemaTypeList{schemaTypeString}}
+	}
+	array := make([]any, n)
+	for i := range array {
+		value, err := gen.generate(items, strGetter, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		array[i] = value
+	}
+	return array, nil
+}
+
+// returns the lowest and highest number the schema allows
+func (gen *SchemaJsonGen) numberBounds(schema *JsonSchema) (float64, float64) {
+	low, high := math.Inf(-1), math.Inf(1)
+	if schema.Minimum != nil {
+		low = *schema.Minimum
+	}
+	if schema.ExclusiveMinimum != nil {
+		low = max(low, math.Nextafter(*schema.ExclusiveMinimum, math.Inf(1)))
+	}
+	if schema.Maximum != nil {
+		high = *schema.Maximum
+	}
+	if schema.ExclusiveMaximum != nil {
+		high = min(high, math.Nextafter(*schema.ExclusiveMaximum, math.Inf(-1)))
+	}
+	switch {
+	case math.IsInf(low, -1) && math.IsInf(high, 1):
+		low, high = 0, defaultSchemaNumberRange
+	case math.IsInf(low, -1):
+		low = high - defaultSchemaNumberRange
+	case math.IsInf(high, 1):
+		high = low + defaultSchemaNumberRange
+	}
+	return low, high
+}
+
+func (gen *SchemaJsonGen) generateString(schema *JsonSchema, strGetter func() string) string {
+	r := gen.rand
+	switch schema.Format {
+	case "date-time":
+		return randTime(r).Format(timestampLayout)
+	case "date":
+		return randTime(r).Format(time.DateOnly)
+	case "time":
+		return randTime(r).Format(time.TimeOnly)
+	case "uuid":
+		return randUUID(r)
+	case "email":
+		return RandNChars(r, GetRandRange(r, 4, 12)) + "@" + RandNChars(r, GetRandRange(r, 4, 10)) + ".com"
+	case "hostname":
+		return RandNChars(r, GetRandRange(r, 4, 10)) + ".com"
+	case "ipv4":
+		return fmt.Sprintf("%d.%d.%d.%d", r.Intn(256), r.Intn(256), r.Intn(256), r.Intn(256))
+	case "uri", "url":
+		return "https://" + RandNChars(r, GetRandRange(r, 4, 10)) + ".com/" + RandNChars(r, GetRandRange(r, 4, 16))
+	case "byte":
+		// random bytes, as the base64 values of typed JSON, of the size which encodes to the maximum length
+		size := GetRandRange(r, gen.config.StrLenMin, gen.config.StrLenMax)
+		if schema.MaxLength != nil {
+			size = base64.StdEncoding.DecodedLen(*schema.MaxLength)
+		}
+		blob := make([]byte, size)
+		r.Read(blob)
+		return base64.StdEncoding.EncodeToString(blob)
+	}
+	if schema.MinLength == nil && schema.MaxLength == nil {
+		return strGetter()
+	}
+	low, high := gen.config.StrLenMin, gen.config.StrLenMax
+	if schema.MinLength != nil {
+		low = *schema.MinLength
+		high = max(high, low)
+	}
+	if schema.MaxLength != nil {
+		high = *schema.MaxLength
+		low = min(low, high)
+	}
+	return RandNChars(r, GetRandRange(r, low, high+1))
+}
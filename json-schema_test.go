@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["id", "created", "status", "score", "count", "user", "tags"],
+	"properties": {
+		"id": {"type": "string", "format": "uuid"},
+		"created": {"type": "string", "format": "date-time"},
+		"status": {"enum": ["active", "disabled"]},
+		"kind": {"const": "order"},
+		"score": {"type": "number", "minimum": 0, "exclusiveMaximum": 5},
+		"count": {"type": ["integer", "null"], "minimum": 1, "maximum": 10},
+		"user": {"$ref": "#/$defs/user"},
+		"tags": {"type": "array", "items": {"type": "string", "minLength": 2, "maxLength": 4}, "minItems": 1, "maxItems": 3},
+		"note": {"type": "string"}
+	},
+	"$defs": {
+		"user": {
+			"allOf": [
+				{"type": "object", "required": ["email"], "properties": {"email": {"type": "string", "format": "email"}}},
+				{"required": ["name"], "properties": {"name": {"type": "string", "minLength": 3, "maxLength": 3}}}
+			]
+		}
+	}
+}`
+
+func writeTestFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func generateSchemaDocs(t *testing.T, schema *JsonSchema, n int) []map[string]any {
+	config := NewJsonGenConfig()
+	config.DepthMax = 3
+	config.StrLenMin, config.StrLenMax = 16, 16
+	config.Seed = 7
+	config.Schema = schema
+	gen := NewJsonGenerator(config)
+	docs := make([]map[string]any, n)
+	for i := range docs {
+		elem, err := gen.JsonGenerate()
+		if err != nil {
+			t.Fatalf("failed to generate json: %v", err)
+		}
+		data, err := json.Marshal(elem)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &docs[i]); err != nil {
+			t.Fatalf("generated invalid json %s: %v", data, err)
+		}
+	}
+	return docs
+}
+
+func TestJsonGenerateSchema(t *testing.T) {
+	schema, err := readJsonSchema(writeTestFile(t, "schema.json", testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uuidRegexp := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	seenNote, seenNullCount := false, false
+	for _, doc := range generateSchemaDocs(t, schema, 200) {
+		if id, _ := doc["id"].(string); !uuidRegexp.MatchString(id) {
+			t.Errorf("id %v is not a uuid", doc["id"])
+		}
+		if _, err := time.Parse(time.RFC3339, doc["created"].(string)); err != nil {
+			t.Errorf("created %v is not a date-time: %v", doc["created"], err)
+		}
+		if status := doc["status"]; status != "active" && status != "disabled" {
+			t.Errorf("status %v is not in the enum", status)
+		}
+		if kind, ok := doc["kind"]; ok && kind != "order" {
+			t.Errorf("kind %v is not the const", kind)
+		}
+		if score := doc["score"].(float64); score < 0 || score >= 5 {
+			t.Errorf("score %v is out of bounds", score)
+		}
+		switch count := doc["count"].(type) {
+		case nil:
+			seenNullCount = true
+		case float64:
+			if count < 1 || count > 10 || count != float64(int(count)) {
+				t.Errorf("count %v is not an integer within bounds", count)
+			}
+		default:
+			t.Errorf("count %v is neither an integer nor null", count)
+		}
+		user := doc["user"].(map[string]any)
+		if email, _ := user["email"].(string); !strings.Contains(email, "@") {
+			t.Errorf("email %v is not an email", user["email"])
+		}
+		if name, _ := user["name"].(string); len(name) != 3 {
+			t.Errorf("name %v is not 3 characters", user["name"])
+		}
+		tags := doc["tags"].([]any)
+		if len(tags) < 1 || len(tags) > 3 {
+			t.Errorf("%d tags, expected between 1 and 3", len(tags))
+		}
+		for _, tag := range tags {
+			if n := len(tag.(string)); n < 2 || n > 4 {
+				t.Errorf("tag %v is not between 2 and 4 characters", tag)
+			}
+		}
+		if note, ok := doc["note"]; ok {
+			seenNote = true
+			if len(note.(string)) != 16 {
+				t.Errorf("note %v does not use the configured string length", note)
+			}
+		}
+	}
+	if !seenNote || !seenNullCount {
+		t.Errorf("optional properties and union types were not all generated, note: %v, null count: %v", seenNote, seenNullCount)
+	}
+}
+
+func TestJsonGenerateSchemaRecursive(t *testing.T) {
+	schema := &JsonSchema{}
+	err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": "string"},
+			"children": {"type": "array", "items": {"$ref": "#"}}
+		}
+	}`), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// optional properties stop being generated past the maximum depth, so the recursion ends
+	generateSchemaDocs(t, schema, 20)
+
+	if err := json.Unmarshal([]byte(`{"type": "object", "required": ["self"], "properties": {"self": {"$ref": "#"}}}`), schema); err != nil {
+		t.Fatal(err)
+	}
+	config := NewJsonGenConfig()
+	config.Schema = schema
+	if _, err := NewJsonGenerator(config).JsonGenerate(); err == nil {
+		t.Errorf("expected an error for a schema which requires itself")
+	}
+}
+
+func TestJsonGenerateSchemaBounds(t *testing.T) {
+	schema := &JsonSchema{}
+	// the root may be null, and the integers span more than an int64 can count
+	err := json.Unmarshal([]byte(`{
+		"type": ["object", "null"],
+		"required": ["full", "beyond"],
+		"properties": {
+			"full": {"type": "integer", "minimum": -9223372036854775808, "maximum": 9223372036854775807},
+			"beyond": {"type": "integer", "minimum": -1e20, "maximum": 1e20}
+		}
+	}`), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range generateSchemaDocs(t, schema, 50) {
+		for _, key := range []string{"full", "beyond"} {
+			if n, ok := doc[key].(float64); !ok || n != math.Trunc(n) {
+				t.Errorf("%s %v is not an integer", key, doc[key])
+			}
+		}
+	}
+
+	nullSchema := &JsonSchema{}
+	if err := json.Unmarshal([]byte(`{"type": ["null"]}`), nullSchema); err != nil {
+		t.Fatal(err)
+	}
+	config := NewJsonGenConfig()
+	config.Schema = nullSchema
+	if _, err := NewJsonGenerator(config).JsonGenerate(); err == nil {
+		t.Errorf("expected an error for a schema which only allows null")
+	}
+}
+
+func TestReadJsonSchemaErrors(t *testing.T) {
+	tests := map[string]string{
+		"invalid json": `{"type": "object"`,
+		"unknown type": `{"type": "object", "properties": {"a": {"type": "date"}}}`,
+		"invalid type": `{"type": 5}`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := readJsonSchema(writeTestFile(t, "schema.json", content)); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestInferSchema(t *testing.T) {
+	path := writeTestFile(t, "example.json", `{
+		"id": 17,
+		"price": -2.5,
+		"name": "widget",
+		"when": "2024-01-02T03:04:05Z",
+		"ok": true,
+		"note": null,
+		"items": [{"sku": "x1", "qty": 2}, {"sku": "y22"}]
+	}`)
+	schema, err := readJsonExample(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ptr := func(f float64) *float64 { return &f }
+	intPtr := func(i int) *int { return &i }
+	exp := &JsonSchema{
+		Type:     schemaTypeList{schemaTypeObject},
+		Required: []string{"id", "items", "name", "note", "ok", "price", "when"},
+		Properties: map[string]*JsonSchema{
+			"id":    {Type: schemaTypeList{schemaTypeInteger}, Minimum: ptr(0), Maximum: ptr(34)},
+			"price": {Type: schemaTypeList{schemaTypeNumber}, Minimum: ptr(-5), Maximum: ptr(0)},
+			"name":  {Type: schemaTypeList{schemaTypeString}, MinLength: intPtr(6), MaxLength: intPtr(6)},
+			"when":  {Type: schemaTypeList{schemaTypeString}, Format: "date-time"},
+			"ok":    {Type: schemaTypeList{schemaTypeBoolean}},
+			"note":  {Type: schemaTypeList{schemaTypeNull}},
+			"items": {
+				Type:     schemaTypeList{schemaTypeArray},
+				MinItems: intPtr(2),
+				MaxItems: intPtr(2),
+				Items: &JsonSchema{
+					Type:     schemaTypeList{schemaTypeObject},
+					Required: []string{"sku"},
+					Properties: map[string]*JsonSchema{
+						"sku": {Type: schemaTypeList{schemaTypeString}, MinLength: intPtr(2), MaxLength: intPtr(3)},
+						"qty": {Type: schemaTypeList{schemaTypeInteger}, Minimum: ptr(0), Maximum: ptr(4)},
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(schema, exp) {
+		actual, _ := json.Marshal(schema)
+		expected, _ := json.Marshal(exp)
+		t.Errorf("inferred schema is not as expected, actual: %s, exp: %s", actual, expected)
+	}
+	// documents generated from the example have the same shape
+	for _, doc := range generateSchemaDocs(t, schema, 20) {
+		if len(doc) != 7 {
+			t.Errorf("generated %d properties, expected 7", len(doc))
+		}
+		if len(doc["items"].([]any)) != 2 {
+			t.Errorf("generated %d items, expected 2", len(doc["items"].([]any)))
+		}
+	}
+}
+
+func TestInferFormat(t *testing.T) {
+	tests := map[string]string{
+		"2024-01-02T03:04:05.123+02:00":        "date-time",
+		"2024-01-02":                           "date",
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c8": "uuid",
+		"someone@example.com":                  "email",
+		"https://example.com/path?q=1":         "uri",
+		"plain text":                           "",
+		"not @ an.email":                       "",
+		"example.com":                          "",
+		"3q2+7w==":                             "byte",
+		"AAECAwQFBgcICQ":                       "",
+		"YWJjZGVmZ2hpamtsbW5vcA==":             "byte",
+		"abcdefgh":                             "",
+		"12345678":                             "",
+	}
+	for s, exp := range tests {
+		if actual := inferFormat(s); actual != exp {
+			t.Errorf("format of %q is %q, expected %q", s, actual, exp)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	// types of generated JSON values
+	jsonTypeString    = "string"
+	jsonTypeInt       = "int"
+	jsonTypeFloat     = "float"
+	jsonTypeBool      = "bool"
+	jsonTypeNull      = "null"
+	jsonTypeArray     = "array"
+	jsonTypeTimestamp = "timestamp"
+	jsonTypeUUID      = "uuid"
+	jsonTypeBase64    = "base64"
+
+	// the max is exclusive, as in the ranges returned by parseRange, so arrays have 1 to 6 elements
+	defaultArrayLenMin = 1
+	defaultArrayLenMax = 7
+
+	// how many times a key is drawn again when the object already has it, after which the
+	// dictionary is assumed to have no unused key left
+	maxKeyDraws = 100
+
+	// generated timestamps fall within the 5 years after this time, so that they do not depend on
+	// when the benchmark runs
+	timestampStart  = 1577836800 // 2020-01-01T00:00:00Z
+	timestampPeriod = 5 * 365 * 24 * 60 * 60
+	// ISO-8601 with milliseconds
+	timestampLayout = "2006-01-02T15:04:05.000Z07:00"
+)
+
+var (
+	jsonValueTypes = []string{
+		jsonTypeString, jsonTypeInt, jsonTypeFloat, jsonTypeBool, jsonTypeNull,
+		jsonTypeArray, jsonTypeTimestamp, jsonTypeUUID, jsonTypeBase64,
+	}
+
+	// the types which arrays are made of
+	jsonPrimitiveTypes = []string{jsonTypeString, jsonTypeInt, jsonTypeFloat, jsonTypeBool}
+)
+
+// JsonValueWeight is the relative number of generated values of a type
+type JsonValueWeight struct {
+	Type   string
+	Weight int
+}
+
+// returns a function which generates a value of a random type, picked according to the weights
+func (conf *JsonGenConfig) valueGetter(r *rand.Rand, strGetter func() string) func() any {
+	var primitives []JsonValueWeight
+	for _, weight := range conf.ValueTypes {
+		for _, primitive := range jsonPrimitiveTypes {
+			if weight.Type == primitive && weight.Weight > 0 {
+				primitives = append(primitives, weight)
+			}
+		}
+	}
+	// arrays are of strings if no primitive type was asked for
+	if len(primitives) == 0 {
+		primitives = []JsonValueWeight{{Type: jsonTypeString, Weight: 1}}
+	}
+	arrayLenMin, arrayLenMax := conf.ArrayLenMin, conf.ArrayLenMax
+	if arrayLenMax == 0 {
+		arrayLenMin, arrayLenMax = defaultArrayLenMin, defaultArrayLenMax
+	}
+	var value func(string) any
+	value = func(valueType string) any {
+		switch valueType {
+		case jsonTypeInt:
+			return r.Intn(1000000)
+		case jsonTypeFloat:
+			return math.Round(r.Float64()*1000000) / 100
+		case jsonTypeBool:
+			return r.Intn(2) == 1
+		case jsonTypeNull:
+			return nil
+		case jsonTypeArray:
+			// every element of an array has the same type, as is usual in real payloads
+			elemType := pickWeighted(r, primitives)
+			array := make([]any, GetRandRange(r, arrayLenMin, arrayLenMax))
+			for i := range array {
+				array[i] = value(elemType)
+			}
+			return array
+		case jsonTypeTimestamp:
+			return randTime(r).Format(timestampLayout)
+		case jsonTypeUUID:
+			return randUUID(r)
+		case jsonTypeBase64:
+			blob := make([]byte, GetRandRange(r, conf.StrLenMin, conf.StrLenMax))
+			r.Read(blob)
+			return base64.StdEncoding.EncodeToString(blob)
+		default:
+			return strGetter()
+		}
+	}
+	return func() any {
+		return value(pickWeighted(r, conf.ValueTypes))
+	}
+}
+
+// returns a random type, where each type is as likely as its share of the total weight
+func pickWeighted(r *rand.Rand, weights []JsonValueWeight) string {
+	total := 0
+	for _, weight := range weights {
+		total += weight.Weight
+	}
+	n := r.Intn(total)
+	for _, weight := range weights {
+		if n < weight.Weight {
+			return weight.Type
+		}
+		n -= weight.Weight
+	}
+	return weights[len(weights)-1].Type
+}
+
+// returns a random time with millisecond precision
+func randTime(r *rand.Rand) time.Time {
+	return time.Unix(timestampStart+r.Int63n(timestampPeriod), r.Int63n(1000)*int64(time.Millisecond)).UTC()
+}
+
+// returns a random version 4 UUID
+func randUUID(r *rand.Rand) string {
+	var b [16]byte
+	r.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// generates an object of typed values, in which children are nested objects under random keys
+func (gen *JsonGen) jsonGenerateTypedRec(strGetter func() string, valueGetter func() any, depth int) (*JsonElement, error) {
+	numChildren := gen.config.numChildrenGetter(gen.rand)
+	numFields := gen.config.numFieldsGetter(gen.rand)
+	out := JsonElement{
+		Values: map[string]any{},
+	}
+	if err := gen.grow(elementOverhead); err != nil {
+		return nil, err
+	}
+	nf := numFields()
+	for i := 0; i < nf || gen.growFields(depth); i++ {
+		// fields and children share the object, so a key which is taken would overwrite one of them
+		key, ok := drawUnusedKey(strGetter, out.Values)
+		if !ok {
+			break
+		}
+		value := valueGetter()
+		out.Values[key] = value
+		if err := gen.grow(len(key) + jsonValueSize(value) + valueOverhead); err != nil {
+			return nil, err
+		}
+	}
+	nc := numChildren(depth)
+	for i := 0; i < nc || gen.growChildren(depth); i++ {
+		if gen.reachedTarget() {
+			break
+		}
+		key, ok := drawUnusedKey(strGetter, out.Values)
+		if !ok {
+			break
+		}
+		if err := gen.grow(len(key) + valueOverhead); err != nil {
+			return nil, err
+		}
+		child, err := gen.jsonGenerateTypedRec(strGetter, valueGetter, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		out.Values[key] = child
+	}
+	return &out, nil
+}
+
+// returns a key which the object does not have yet, or false if none was drawn, which happens when
+// a small dictionary has no unused key left
+func drawUnusedKey(strGetter func() string, values map[string]any) (string, bool) {
+	for range maxKeyDraws {
+		key := strGetter()
+		if _, ok := values[key]; !ok {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// returns the size of a value when marshalled
+func jsonValueSize(value any) int {
+	// generated strings have nothing to escape
+	if s, ok := value.(string); ok {
+		return len(s) + len(`""`)
+	}
+	data, _ := json.Marshal(value)
+	return len(data)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// implementations of the DEFLATE based formats, which prefix their result names
+	stdImpl = "std"
+	kpImpl  = "kp"
+)
+
+var (
+	// levels supported by the DEFLATE based codecs, other than the default level
+	deflateLevels = []int{
+		flate.HuffmanOnly, flate.NoCompression,
+		1, 2, 3, 4, 5, 6, 7, 8, 9,
+	}
+)
+
+// deflateFormat is a DEFLATE based format, i.e. raw DEFLATE, gzip or zlib, of either the standard
+// library or the klauspost implementation. They share their levels and API, so one Benchmarker
+// runs all of them through their writer and reader constructors
+type deflateFormat struct {
+	// name the codec is selected by, e.g. kp-gzip
+	codec  string
+	impl   string
+	format string
+	// level which the default compression level stands for in this implementation
+	defaultLevel int
+	newWriter    func(w io.Writer, level int) (resetWriter, error)
+	newReader    func(r io.Reader) (io.ReadCloser, error)
+}
+
+// registers a DEFLATE based format as a codec supporting every DEFLATE level
+func registerDeflateCodec(format *deflateFormat) {
+	RegisterCodec(&Codec{
+		Name:         format.codec,
+		DefaultLevel: flate.DefaultCompression,
+		Levels:       deflateLevels,
+		New: func(level int, _ map[string]string) (Benchmarker, error) {
+			return NewDeflater(format, level), nil
+		},
+	})
+}
+
+// returns the result name of the format at a level, which always includes the implementation and
+// the level so that the same format and level of both implementations can be compared, e.g. std-gzip-6
+func (format *deflateFormat) runnerName(level int) string {
+	switch level {
+	case flate.DefaultCompression:
+		level = format.defaultLevel
+	case flate.HuffmanOnly:
+		return fmt.Sprintf("%s-%s-huffman-only", format.impl, format.format)
+	}
+	return fmt.Sprintf("%s-%s-%d", format.impl, format.format, level)
+}
+
+// Deflater implements the Benchmarker interface for a DEFLATE based format
+type Deflater struct {
+	format *deflateFormat
+	level  int
+}
+
+func NewDeflater(format *deflateFormat, level int) *Deflater {
+	return &Deflater{
+		format: format,
+		level:  level,
+	}
+}
+
+func (d *Deflater) RunBenchmark(input []byte, opts *RunOptions) (*BenchmarkResult, error) {
+	return runRoundTrip(d.format.runnerName(d.level), input, d.compress, d.decompress, opts)
+}
+
+func (d *Deflater) NewReusable() (*ReusableCodec, error) {
+	zw, err := d.format.newWriter(nil, d.level)
+	if err != nil {
+		return nil, err
+	}
+	// a gzip or zlib reader can only be created from a valid stream, so every reader starts
+	// with an empty one
+	empty, _, err := d.compress([]byte{})
+	if err != nil {
+		return nil, err
+	}
+	zr, err := d.format.newReader(bytes.NewReader(empty))
+	if err != nil {
+		return nil, err
+	}
+	return &ReusableCodec{
+		Name:    d.format.runnerName(d.level),
+		Encoder: zw,
+		ResetDecoder: func(r io.Reader) (io.Reader, error) {
+			return zr, resetDeflateReader(zr, r)
+		},
+	}, nil
+}
+
+// points a reader of any of the formats at a new compressed input
+func resetDeflateReader(zr io.Reader, r io.Reader) error {
+	switch zr := zr.(type) {
+	case interface{ Reset(io.Reader) error }:
+		// gzip
+		return zr.Reset(r)
+	case interface {
+		Reset(io.Reader, []byte) error
+	}:
+		// raw DEFLATE and zlib
+		return zr.Reset(r, nil)
+	}
+	return fmt.Errorf("reader %T cannot be reset", zr)
+}
+
+func (d *Deflater) compress(input []byte) ([]byte, time.Duration, error) {
+	var buf bytes.Buffer
+	t0 := time.Now()
+	zw, err := d.format.newWriter(&buf, d.level)
+	if err != nil {
+		return nil, 0, err
+	}
+	_, err = zw.Write(input)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), time.Since(t0), nil
+}
+
+func (d *Deflater) decompress(inputBytes []byte) ([]byte, time.Duration, error) {
+	t0 := time.Now()
+	zr, err := d.format.newReader(bytes.NewReader(inputBytes))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer zr.Close()
+	outBytes, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, 0, err
+	}
+	return outBytes, time.Since(t0), nil
+}
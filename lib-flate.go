@@ -0,0 +1,24 @@
+package main
+
+import (
+	"compress/flate"
+	"io"
+)
+
+// raw DEFLATE streams, without the gzip or zlib framing
+var stdFlate = &deflateFormat{
+	codec:        "flate",
+	impl:         stdImpl,
+	format:       "flate",
+	defaultLevel: 6,
+	newWriter: func(w io.Writer, level int) (resetWriter, error) {
+		return flate.NewWriter(w, level)
+	},
+	newReader: func(r io.Reader) (io.ReadCloser, error) {
+		return flate.NewReader(r), nil
+	},
+}
+
+func init() {
+	registerDeflateCodec(stdFlate)
+}
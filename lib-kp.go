@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io"
+
+	kpflate "github.com/klauspost/compress/flate"
+	kpgzip "github.com/klauspost/compress/gzip"
+	kpzlib "github.com/klauspost/compress/zlib"
+)
+
+// the drop-in klauspost replacements of the standard library DEFLATE based formats, which
+// default to level 5 rather than 6
+var (
+	kpFlate = &deflateFormat{
+		codec:        "kp-flate",
+		impl:         kpImpl,
+		format:       "flate",
+		defaultLevel: 5,
+		newWriter: func(w io.Writer, level int) (resetWriter, error) {
+			return kpflate.NewWriter(w, level)
+		},
+		newReader: func(r io.Reader) (io.ReadCloser, error) {
+			return kpflate.NewReader(r), nil
+		},
+	}
+	kpGzip = &deflateFormat{
+		codec:        "kp-gzip",
+		impl:         kpImpl,
+		format:       "gzip",
+		defaultLevel: 5,
+		newWriter: func(w io.Writer, level int) (resetWriter, error) {
+			return kpgzip.NewWriterLevel(w, level)
+		},
+		newReader: func(r io.Reader) (io.ReadCloser, error) {
+			return kpgzip.NewReader(r)
+		},
+	}
+	kpZlib = &deflateFormat{
+		codec:        "kp-zlib",
+		impl:         kpImpl,
+		format:       "zlib",
+		defaultLevel: 5,
+		newWriter: func(w io.Writer, level int) (resetWriter, error) {
+			return kpzlib.NewWriterLevel(w, level)
+		},
+		newReader: kpzlib.NewReader,
+	}
+)
+
+func init() {
+	registerDeflateCodec(kpFlate)
+	registerDeflateCodec(kpGzip)
+	registerDeflateCodec(kpZlib)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+)
+
+const (
+	// s2 has no numeric levels, so the encoder modes are numbered from fastest to smallest
+	s2LevelDefault = 1
+	s2LevelBetter  = 2
+	s2LevelBest    = 3
+)
+
+func init() {
+	RegisterCodec(&Codec{
+		Name:         "s2",
+		DefaultLevel: s2LevelDefault,
+		Levels:       []int{s2LevelDefault, s2LevelBetter, s2LevelBest},
+		New: func(level int, _ map[string]string) (Benchmarker, error) {
+			return NewS2Runner(level), nil
+		},
+	})
+}
+
+// S2er implements the Benchmarker interface
+type S2er struct {
+	level int
+}
+
+func NewS2Runner(level int) *S2er {
+	return &S2er{
+		level: level,
+	}
+}
+
+func (s *S2er) RunBenchmark(input []byte, opts *RunOptions) (*BenchmarkResult, error) {
+	return runS2(input, s.level, opts)
+}
+
+func (s *S2er) NewReusable() (*ReusableCodec, error) {
+	zr := s2.NewReader(nil)
+	return &ReusableCodec{
+		Name:    s2RunnerName(s.level),
+		Encoder: s2.NewWriter(nil, s2WriterOptions(s.level)...),
+		ResetDecoder: func(r io.Reader) (io.Reader, error) {
+			zr.Reset(r)
+			return zr, nil
+		},
+	}, nil
+}
+
+// like every codec with levels, the result name always includes the level, e.g. s2-2 for the better mode
+func s2RunnerName(level int) string {
+	return fmt.Sprintf("s2-%d", level)
+}
+
+// returns the writer options for the given s2 level
+func s2WriterOptions(level int) []s2.WriterOption {
+	switch level {
+	case s2LevelBetter:
+		return []s2.WriterOption{s2.WriterBetterCompression()}
+	case s2LevelBest:
+		return []s2.WriterOption{s2.WriterBestCompression()}
+	default:
+		return nil
+	}
+}
+
+func runS2(input []byte, level int, opts *RunOptions) (*BenchmarkResult, error) {
+	runnerName := s2RunnerName(level)
+	compress := func(b []byte) ([]byte, time.Duration, error) {
+		return compressS2(b, level)
+	}
+	return runRoundTrip(runnerName, input, compress, decompressS2, opts)
+}
+
+func compressS2(input []byte, level int) ([]byte, time.Duration, error) {
+	var buf bytes.Buffer
+	t0 := time.Now()
+	zw := s2.NewWriter(&buf, s2WriterOptions(level)...)
+	_, err := zw.Write(input)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), time.Since(t0), nil
+}
+
+func decompressS2(inputBytes []byte) ([]byte, time.Duration, error) {
+	t0 := time.Now()
+	reader := bytes.NewReader(inputBytes)
+	zr := s2.NewReader(reader)
+	outBytes, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, 0, err
+	}
+	return outBytes, time.Since(t0), nil
+}
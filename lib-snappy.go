@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+)
+
+const (
+	// snappy has a single compression mode
+	snappyLevelDefault = 1
+)
+
+func init() {
+	RegisterCodec(&Codec{
+		Name:         "snappy",
+		DefaultLevel: snappyLevelDefault,
+		Levels:       []int{snappyLevelDefault},
+		New: func(_ int, _ map[string]string) (Benchmarker, error) {
+			return NewSnappyRunner(), nil
+		},
+	})
+}
+
+// Snappyer implements the Benchmarker interface
+type Snappyer struct {
+}
+
+func NewSnappyRunner() *Snappyer {
+	return &Snappyer{}
+}
+
+func (s *Snappyer) RunBenchmark(input []byte, opts *RunOptions) (*BenchmarkResult, error) {
+	return runSnappy(input, opts)
+}
+
+func (s *Snappyer) NewReusable() (*ReusableCodec, error) {
+	zr := snappy.NewReader(nil)
+	return &ReusableCodec{
+		Name:    "snappy",
+		Encoder: snappy.NewBufferedWriter(nil),
+		ResetDecoder: func(r io.Reader) (io.Reader, error) {
+			zr.Reset(r)
+			return zr, nil
+		},
+	}, nil
+}
+
+func runSnappy(input []byte, opts *RunOptions) (*BenchmarkResult, error) {
+	return runRoundTrip("snappy", input, compressSnappy, decompressSnappy, opts)
+}
+
+func compressSnappy(input []byte) ([]byte, time.Duration, error) {
+	var buf bytes.Buffer
+	t0 := time.Now()
+	zw := snappy.NewBufferedWriter(&buf)
+	_, err := zw.Write(input)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), time.Since(t0), nil
+}
+
+func decompressSnappy(inputBytes []byte) ([]byte, time.Duration, error) {
+	t0 := time.Now()
+	reader := bytes.NewReader(inputBytes)
+	zr := snappy.NewReader(reader)
+	outBytes, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, 0, err
+	}
+	return outBytes, time.Since(t0), nil
+}
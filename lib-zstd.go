@@ -2,53 +2,240 @@ package main
 
 import (
 	"bytes"
-	"compress/zlib"
+	"fmt"
 	"io"
+	"math/bits"
+	"os"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/klauspost/compress/zstd"
 )
 
+const (
+	// values of the zstd mode option
+	zstdModeStream = "stream"
+	zstdModeAll    = "all"
+)
+
+func init() {
+	RegisterCodec(&Codec{
+		Name:         "zstd",
+		DefaultLevel: int(zstd.SpeedDefault),
+		Levels: []int{
+			int(zstd.SpeedFastest), int(zstd.SpeedDefault),
+			int(zstd.SpeedBetterCompression), int(zstd.SpeedBestCompression),
+		},
+		Options: []string{
+			"dict", "concurrency", "window", "crc", "mode", "decoder-concurrency", "decoder-max-memory",
+		},
+		New: func(level int, opts map[string]string) (Benchmarker, error) {
+			var dict []byte
+			if dictFile, ok := opts["dict"]; ok {
+				var err error
+				if dict, err = os.ReadFile(dictFile); err != nil {
+					return nil, fmt.Errorf("error reading dictionary file: %v", err)
+				}
+			}
+			zopts, err := parseZstdOptions(opts)
+			if err != nil {
+				return nil, err
+			}
+			return NewZstdRunner(zstd.EncoderLevel(level), dict, zopts), nil
+		},
+	})
+}
+
+// ZstdOptions configures the zstd encoder and decoder, zero values keep the library defaults
+type ZstdOptions struct {
+	// number of goroutines the encoder may use
+	Concurrency int
+	WindowSize  int
+	NoCRC       bool
+	// compress and decompress the whole input at once with EncodeAll and DecodeAll instead of streaming
+	EncodeAll bool
+	// number of goroutines the decoder may use, nil keeps the library default while 0 uses GOMAXPROCS
+	DecoderConcurrency *int
+	DecoderMaxMemory   uint64
+	// appended to the runner name so that different configurations can be told apart
+	suffix string
+}
+
+// parses the zstd codec options other than dict
+func parseZstdOptions(opts map[string]string) (*ZstdOptions, error) {
+	zopts := &ZstdOptions{}
+	keys := make([]string, 0, len(opts))
+	for key := range opts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := opts[key]
+		var err error
+		switch key {
+		case "dict":
+			continue
+		case "concurrency":
+			zopts.Concurrency, err = parseZstdInt(key, value, 1)
+		case "window":
+			var size uint64
+			size, err = parseByteSize(value, key)
+			if err == nil && (size < zstd.MinWindowSize || size > zstd.MaxWindowSize || bits.OnesCount64(size) != 1) {
+				err = fmt.Errorf("invalid value '%s' for %s: must be a power of 2 between %d and %d bytes",
+					value, key, zstd.MinWindowSize, zstd.MaxWindowSize)
+			}
+			zopts.WindowSize = int(size)
+		case "crc":
+			var crc bool
+			crc, err = strconv.ParseBool(value)
+			zopts.NoCRC = !crc
+		case "mode":
+			if value != zstdModeStream && value != zstdModeAll {
+				err = fmt.Errorf("invalid value '%s' for %s: must be %s or %s", value, key, zstdModeStream, zstdModeAll)
+			}
+			zopts.EncodeAll = value == zstdModeAll
+		case "decoder-concurrency":
+			var n int
+			n, err = parseZstdInt(key, value, 0)
+			zopts.DecoderConcurrency = &n
+		case "decoder-max-memory":
+			zopts.DecoderMaxMemory, err = parseByteSize(value, key)
+		}
+		if err != nil {
+			return nil, err
+		}
+		zopts.suffix += fmt.Sprintf(":%s=%s", key, value)
+	}
+	return zopts, nil
+}
+
+func parseZstdInt(key, value string, min int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min {
+		return 0, fmt.Errorf("invalid value '%s' for %s: must be an integer of at least %d", value, key, min)
+	}
+	return n, nil
+}
+
 // Zstder implements the Benchmarker interface
 type Zstder struct {
+	level zstd.EncoderLevel
+	dict  []byte
+	opts  *ZstdOptions
 }
 
-func NewZstdRunner() *Zstder {
-	return &Zstder{}
+// dict may be nil to compress without a dictionary, and opts may be nil to use the library defaults
+func NewZstdRunner(level zstd.EncoderLevel, dict []byte, opts *ZstdOptions) *Zstder {
+	if opts == nil {
+		opts = &ZstdOptions{}
+	}
+	return &Zstder{
+		level: level,
+		dict:  dict,
+		opts:  opts,
+	}
 }
 
-func (z *Zstder) RunBenchmark(input []byte) (*BenchmarkResult, error) {
-	return runZstd(input, zlib.BestSpeed)
+func (z *Zstder) RunBenchmark(input []byte, runOpts *RunOptions) (*BenchmarkResult, error) {
+	return runZstd(input, z.level, z.dict, z.opts, runOpts)
 }
 
-func runZstd(input []byte, level int) (*BenchmarkResult, error) {
-	zstdBytes, zstdCompTime, err := compressZstd(input, level)
+func (z *Zstder) NewReusable() (*ReusableCodec, error) {
+	zw, err := zstd.NewWriter(nil, zstdEncoderOptions(z.level, z.dict, z.opts)...)
 	if err != nil {
 		return nil, err
 	}
-	zstdSize := len(zstdBytes)
-	_, zstdDecompTime, err := decompressZstd(zstdBytes)
+	zr, err := zstd.NewReader(nil, zstdDecoderOptions(z.dict, z.opts)...)
 	if err != nil {
 		return nil, err
 	}
-	zstdRatio := float64(zstdSize) / float64(len(input))
-	res := BenchmarkResult{
-		DecompressTime: zstdDecompTime,
-		CompressTime:   zstdCompTime,
-		CompressedSize: zstdSize,
-		Ratio:          zstdRatio,
-		Name:           "zstd",
+	codec := &ReusableCodec{
+		Name:    zstdRunnerName(z.level, z.dict, z.opts),
+		Encoder: zw,
+		ResetDecoder: func(r io.Reader) (io.Reader, error) {
+			return zr, zr.Reset(r)
+		},
+		// the decoder keeps its goroutines running until it is closed
+		Close: zr.Close,
+	}
+	if z.opts.EncodeAll {
+		codec.Compress = func(input []byte) ([]byte, error) {
+			return zw.EncodeAll(input, nil), nil
+		}
+		codec.Decompress = func(input []byte) ([]byte, error) {
+			return zr.DecodeAll(input, nil)
+		}
+	}
+	return codec, nil
+}
+
+func zstdRunnerName(level zstd.EncoderLevel, dict []byte, opts *ZstdOptions) string {
+	// the level is always included, as the number it is selected by, e.g. zstd-2 for the default level
+	runnerName := fmt.Sprintf("zstd-%d", level) + opts.suffix
+	// the dictionary suffix comes last, so that the name without it is the same codec without a dictionary
+	if dict != nil {
+		runnerName += zstdDictSuffix
 	}
-	return &res, nil
+	return runnerName
 }
 
-func compressZstd(input []byte, level int) ([]byte, time.Duration, error) {
+func zstdEncoderOptions(level zstd.EncoderLevel, dict []byte, opts *ZstdOptions) []zstd.EOption {
+	eopts := []zstd.EOption{zstd.WithEncoderLevel(level)}
+	if dict != nil {
+		eopts = append(eopts, zstd.WithEncoderDict(dict))
+	}
+	if opts.Concurrency != 0 {
+		eopts = append(eopts, zstd.WithEncoderConcurrency(opts.Concurrency))
+	}
+	if opts.WindowSize != 0 {
+		eopts = append(eopts, zstd.WithWindowSize(opts.WindowSize))
+	}
+	if opts.NoCRC {
+		eopts = append(eopts, zstd.WithEncoderCRC(false))
+	}
+	return eopts
+}
+
+func zstdDecoderOptions(dict []byte, opts *ZstdOptions) []zstd.DOption {
+	var dopts []zstd.DOption
+	if dict != nil {
+		dopts = append(dopts, zstd.WithDecoderDicts(dict))
+	}
+	if opts.DecoderConcurrency != nil {
+		dopts = append(dopts, zstd.WithDecoderConcurrency(*opts.DecoderConcurrency))
+	}
+	if opts.DecoderMaxMemory != 0 {
+		dopts = append(dopts, zstd.WithDecoderMaxMemory(opts.DecoderMaxMemory))
+	}
+	return dopts
+}
+
+func runZstd(input []byte, level zstd.EncoderLevel, dict []byte, opts *ZstdOptions, runOpts *RunOptions) (*BenchmarkResult, error) {
+	runnerName := zstdRunnerName(level, dict, opts)
+	compress := func(b []byte) ([]byte, time.Duration, error) {
+		return compressZstd(b, level, dict, opts)
+	}
+	decompress := func(b []byte) ([]byte, time.Duration, error) {
+		return decompressZstd(b, dict, opts)
+	}
+	return runRoundTrip(runnerName, input, compress, decompress, runOpts)
+}
+
+func compressZstd(input []byte, level zstd.EncoderLevel, dict []byte, opts *ZstdOptions) ([]byte, time.Duration, error) {
 	var buf bytes.Buffer
 	t0 := time.Now()
-	zw, err := zstd.NewWriter(&buf)
+	zw, err := zstd.NewWriter(&buf, zstdEncoderOptions(level, dict, opts)...)
 	if err != nil {
 		return nil, 0, err
 	}
+	if opts.EncodeAll {
+		out := zw.EncodeAll(input, nil)
+		if err := zw.Close(); err != nil {
+			return nil, 0, err
+		}
+		return out, time.Since(t0), nil
+	}
 	_, err = zw.Write(input)
 	if err != nil {
 		return nil, 0, err
@@ -59,14 +246,24 @@ func compressZstd(input []byte, level int) ([]byte, time.Duration, error) {
 	return buf.Bytes(), time.Since(t0), nil
 }
 
-func decompressZstd(inputBytes []byte) ([]byte, time.Duration, error) {
+func decompressZstd(inputBytes []byte, dict []byte, opts *ZstdOptions) ([]byte, time.Duration, error) {
 	t0 := time.Now()
-	reader := bytes.NewReader(inputBytes)
-	zr, err := zstd.NewReader(reader)
+	var reader io.Reader
+	if !opts.EncodeAll {
+		reader = bytes.NewReader(inputBytes)
+	}
+	zr, err := zstd.NewReader(reader, zstdDecoderOptions(dict, opts)...)
 	if err != nil {
 		return nil, 0, err
 	}
 	defer zr.Close()
+	if opts.EncodeAll {
+		outBytes, err := zr.DecodeAll(inputBytes, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		return outBytes, time.Since(t0), nil
+	}
 	outBytes, err := io.ReadAll(zr)
 	if err != nil {
 		return nil, 0, err
@@ -1,6 +1,10 @@
 package main
 
+import "os"
+
 func main() {
 	cmd := NewBenchCmd()
-	cmd.Execute()
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// default maximum dictionary size, matching the klauspost dictionary builder
+	defaultZstdDictSize = 114688
+	// only the beginning of each sample matters when training, so samples are truncated to this length
+	zstdDictSampleLen = 32768
+	// minimum match length indexed by the dictionary builder
+	zstdDictHashBytes = 6
+	// appended to the name of results which were compressed with a trained dictionary
+	zstdDictSuffix = "+dict"
+)
+
+// ZstdDict is a zstd dictionary trained from a set of sample inputs
+type ZstdDict struct {
+	Data      []byte        `json:"-"`
+	Size      int           `json:"size"`
+	Samples   int           `json:"samples"`
+	TrainTime time.Duration `json:"train_time_ns"`
+}
+
+// trains a zstd dictionary of at most maxSize bytes from the given samples
+func trainZstdDict(samples [][]byte, maxSize int) (*ZstdDict, error) {
+	truncated := make([][]byte, 0, len(samples))
+	for _, sample := range samples {
+		if len(sample) > zstdDictSampleLen {
+			sample = sample[:zstdDictSampleLen]
+		}
+		truncated = append(truncated, sample)
+	}
+	t0 := time.Now()
+	data, err := dict.BuildZstdDict(truncated, dict.Options{
+		MaxDictSize: maxSize,
+		HashBytes:   zstdDictHashBytes,
+		ZstdLevel:   zstd.SpeedDefault,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to train zstd dictionary: %v", err)
+	}
+	return &ZstdDict{
+		Data:      data,
+		Size:      len(data),
+		Samples:   len(samples),
+		TrainTime: time.Since(t0),
+	}, nil
+}
+
+// returns a dictionary-compressed counterpart for every zstd codec in specs, or for the
+// default zstd level if specs contains no zstd codec
+func newZstdDictBenchmarkers(specs []CodecSpec, zdict *ZstdDict) ([]Benchmarker, error) {
+	codec := codecRegistry["zstd"]
+	benchmarkers := []Benchmarker{}
+	for _, spec := range specs {
+		if spec.Name != codec.Name {
+			continue
+		}
+		level := codec.DefaultLevel
+		if spec.HasLevel {
+			level = spec.Level
+		}
+		opts, err := parseZstdOptions(spec.Options)
+		if err != nil {
+			return nil, err
+		}
+		benchmarkers = append(benchmarkers, NewZstdRunner(zstd.EncoderLevel(level), zdict.Data, opts))
+	}
+	if len(benchmarkers) == 0 {
+		benchmarkers = append(benchmarkers,
+			NewZstdRunner(zstd.SpeedDefault, nil, nil),
+			NewZstdRunner(zstd.SpeedDefault, zdict.Data, nil),
+		)
+	}
+	return benchmarkers, nil
+}
+
+// prints the dictionary statistics, and the ratio of each dictionary result against the same codec without a dictionary
+func printZstdDictSummary(w io.Writer, zdict *ZstdDict, results []*BenchmarkResult) {
+	fmt.Fprintf(w, "Zstd dictionary: %s trained from %d samples in %s\n", formatBytes(zdict.Size), zdict.Samples, zdict.TrainTime)
+	byName := map[string]*BenchmarkResult{}
+	for _, result := range results {
+		byName[result.Name] = result
+	}
+	for _, result := range results {
+		baseName, ok := strings.CutSuffix(result.Name, zstdDictSuffix)
+		if !ok {
+			continue
+		}
+		base, ok := byName[baseName]
+		if !ok {
+			continue
+		}
+		improvement := 0.0
+		if base.Ratio != 0 {
+			improvement = (base.Ratio - result.Ratio) / base.Ratio
+		}
+		fmt.Fprintf(w, "%s: ratio %s without dictionary, %s with dictionary (%s smaller)\n",
+			baseName, formatRatio(base.Ratio), formatRatio(result.Ratio), formatRatio(improvement))
+	}
+}